@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/service"
+)
+
+const defaultBackfillConcurrency = 4
+
+// runBackfill downloads all missing images in the date range provided by the command line arguments.
+func runBackfill(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	from := fs.String("from", "", "first date of the range in YYYY-MM-DD format")
+	to := fs.String("to", "", "last date of the range in YYYY-MM-DD format, the current APOD date by default")
+	concurrency := fs.Int("concurrency", defaultBackfillConcurrency, "number of concurrent downloads")
+	_ = fs.Parse(args)
+
+	fromDate, err := time.Parse(time.DateOnly, *from)
+	if err != nil {
+		log.Fatalf("parse from: %v", err)
+	}
+
+	var toDate time.Time
+	if *to != "" {
+		toDate, err = time.Parse(time.DateOnly, *to)
+		if err != nil {
+			log.Fatalf("parse to: %v", err)
+		}
+	}
+
+	serviceCfg, err := service.InitConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	imageService, _, err := initService(*serviceCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	err = imageService.Backfill(ctx, fromDate, toDate, *concurrency)
+
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), timeForDownloadsDrain)
+	defer closeCancel()
+
+	if closeErr := imageService.Close(closeCtx); closeErr != nil {
+		log.Print(closeErr)
+	}
+
+	if err != nil {
+		log.Fatal(err) //nolint:gocritic // exit after defer is not important there
+	}
+}
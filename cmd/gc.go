@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/Dyleme/apod.git/pkg/service"
+)
+
+// runGC removes files of the storage which are not referenced by the database.
+func runGC(args []string) {
+	serviceCfg, err := service.InitConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	grace := fs.Duration("grace", serviceCfg.GCGracePeriod, "minimal age of the removed files")
+	dryRun := fs.Bool("dry-run", false, "only report files which would be removed")
+	_ = fs.Parse(args)
+
+	imageService, _, err := initService(*serviceCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	report, err := imageService.CollectGarbage(ctx, *grace, *dryRun)
+	if report != nil {
+		action := "deleted"
+		if report.DryRun {
+			action = "would be deleted"
+		}
+
+		for _, name := range report.Orphaned {
+			fmt.Printf("%s %s\n", action, name)
+		}
+
+		fmt.Printf("scanned %v files, %v orphaned, %v deleted\n", report.Scanned, len(report.Orphaned), report.Deleted)
+	}
+
+	if err != nil {
+		log.Fatal(err) //nolint:gocritic // exit after defer is not important there
+	}
+}
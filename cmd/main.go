@@ -2,14 +2,23 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"sync"
+	"time"
+	_ "time/tzdata" // timezones for the daily sync in images without tzdata
 
 	"github.com/Dyleme/apod.git/pkg/apod-service"
+	"github.com/Dyleme/apod.git/pkg/apod-service/apodtest"
 	"github.com/Dyleme/apod.git/pkg/database/postgres"
 	"github.com/Dyleme/apod.git/pkg/handler"
+	"github.com/Dyleme/apod.git/pkg/handler/adminhandler"
 	"github.com/Dyleme/apod.git/pkg/handler/imagehandler"
 	"github.com/Dyleme/apod.git/pkg/repository"
+	"github.com/Dyleme/apod.git/pkg/scheduler"
 	"github.com/Dyleme/apod.git/pkg/server"
 	"github.com/Dyleme/apod.git/pkg/service"
 	"github.com/Dyleme/apod.git/pkg/storage"
@@ -17,34 +26,211 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	timeForDownloadsDrain = 10 * time.Second
+	timeForStorageInit    = 30 * time.Second
+)
+
+// backgroundJob runs until the context is done.
+type backgroundJob interface {
+	Run(ctx context.Context)
+}
+
 func main() {
-	apodService := initAPOD()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backfill":
+			runBackfill(os.Args[2:])
+
+			return
+		case "gc":
+			runGC(os.Args[2:])
+
+			return
+		case "verify":
+			runVerify(os.Args[2:])
+
+			return
+		case "renditions":
+			runRenditions(os.Args[2:])
+
+			return
+		}
+	}
+
+	fakeAPOD := flag.Bool("fake-apod", false, "run against the fake APOD api instead of NASA for offline development")
+	flag.Parse()
 
-	stor, err := initMinio()
+	var apodOpts []apod.Option
+
+	if *fakeAPOD {
+		fakeServer := apodtest.NewServer()
+		defer fakeServer.Close()
+
+		logrus.Info("fake apod api is running at ", fakeServer.URL)
+
+		apodOpts = append(apodOpts, apod.WithBaseURL(fakeServer.URL))
+	}
+
+	serviceCfg, err := service.InitConfig()
 	if err != nil {
-		log.Fatal(err)
+		log.Fatal(err) //nolint:gocritic // exit after defer is not important there
 	}
 
-	repo, err := initRepository()
+	imageService, files, err := initService(*serviceCfg, apodOpts...)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	imageService := service.New(apodService, repo, stor)
 	imageHandler := imagehandler.New(imageService)
-	hand := handler.New(imageHandler)
+
+	var mediaHandler handler.MediaHandler
+	if serviceCfg.MediaBaseURL != "" {
+		mediaHandler = imageHandler
+	}
+
+	var adminHandler handler.AdminHandler
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		adminHandler = adminhandler.New(imageService, token)
+	}
+
+	hand := handler.New(imageHandler, mediaHandler, adminHandler, files)
+
+	syncCfg := scheduler.Config{RetryInterval: serviceCfg.SyncRetryInterval}
+	jobs := []backgroundJob{scheduler.New(syncCfg, imageService)}
+
+	if serviceCfg.GCInterval > 0 {
+		jobs = append(jobs, scheduler.NewPeriodic("storage gc", serviceCfg.GCInterval, func(ctx context.Context) error {
+			report, err := imageService.CollectGarbage(ctx, serviceCfg.GCGracePeriod, false)
+			if report != nil {
+				logrus.Infof("storage gc: scanned %v, deleted %v", report.Scanned, report.Deleted)
+			}
+
+			return err
+		}))
+	}
 
 	appPort := os.Getenv("APP_PORT")
 	serv := server.New(appPort, hand.InitRouters())
 
-	err = serv.Run(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var jobsWg sync.WaitGroup
+
+	for _, j := range jobs {
+		jobsWg.Add(1)
+
+		go func(j backgroundJob) {
+			defer jobsWg.Done()
+			j.Run(ctx)
+		}(j)
+	}
+
+	err = serv.Run(ctx)
+
+	cancel()
+	jobsWg.Wait()
+
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), timeForDownloadsDrain)
+	defer closeCancel()
+
+	if closeErr := imageService.Close(closeCtx); closeErr != nil {
+		logrus.Error("close service: ", closeErr)
+	}
+
 	if err != nil {
 		logrus.Fatal("error on server", err)
 	}
 }
 
-func initAPOD() *apod.Service {
-	return apod.NewService(os.Getenv("NASA_API_KEY"))
+// initService initializes the service and its dependencies,
+// provided options are applied to the APOD client after the configured ones.
+// The returned handler serves the stored files, it is nil if the storage serves them itself.
+func initService(cfg service.Config, apodOpts ...apod.Option) (*service.Service, http.Handler, error) {
+	apodService, err := initAPOD(apodOpts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stor, urls, files, err := initStorage()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repo, err := initRepository()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return service.New(apodService, repo, stor, urls, cfg), files, nil
+}
+
+func initAPOD(extraOpts ...apod.Option) (*apod.Service, error) {
+	apodCfg, err := apod.InitConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []apod.Option{apod.WithUserAgent(os.Getenv("NASA_USER_AGENT"))}
+
+	if baseURL := os.Getenv("NASA_BASE_URL"); baseURL != "" {
+		opts = append(opts, apod.WithBaseURL(baseURL))
+	}
+
+	if timeout := os.Getenv("NASA_REQUEST_TIMEOUT"); timeout != "" {
+		t, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("cant parse nasa request timeout %q: %w", timeout, err)
+		}
+
+		opts = append(opts, apod.WithTimeout(t))
+	}
+
+	opts = append(opts, extraOpts...)
+
+	return apod.NewService(*apodCfg, opts...), nil
+}
+
+// initStorage initializes the storage selected by STORAGE_DRIVER and the builder of links to its files.
+// The returned handler serves the stored files if the storage needs the application to serve them.
+func initStorage() (service.Storager, *storage.URLBuilder, http.Handler, error) {
+	urlCfg := storage.InitURLConfig()
+
+	baseURL := func(driverURL string) string {
+		if urlCfg.BaseURL != "" {
+			return urlCfg.BaseURL
+		}
+
+		return driverURL
+	}
+
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "minio":
+		stor, err := initMinio()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		var presigner storage.Presigner
+		if stor.Private() {
+			presigner = stor
+		}
+
+		return stor, storage.NewURLBuilder(baseURL(stor.BaseURL()), presigner), nil, nil
+	case "fs":
+		stor, err := storage.NewFSStorage(*storage.InitFSConfig())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		return stor, storage.NewURLBuilder(baseURL(stor.BaseURL()), nil), stor.Handler(), nil
+	case "memory":
+		stor := storage.NewMemoryStorage()
+
+		return stor, storage.NewURLBuilder(baseURL(stor.BaseURL()), nil), nil, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
 }
 
 func initMinio() (*storage.Minio, error) {
@@ -58,6 +244,13 @@ func initMinio() (*storage.Minio, error) {
 		return nil, err
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), timeForStorageInit)
+	defer cancel()
+
+	if err := stor.ApplyBucketPolicies(ctx); err != nil {
+		return nil, err
+	}
+
 	return stor, nil
 }
 
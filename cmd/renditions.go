@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/Dyleme/apod.git/pkg/service"
+)
+
+// runRenditions generates renditions of the already stored images.
+func runRenditions(args []string) {
+	fs := flag.NewFlagSet("renditions", flag.ExitOnError)
+	all := fs.Bool("all", false, "regenerate renditions of all images, not only of images without them")
+	concurrency := fs.Int("concurrency", defaultBackfillConcurrency, "number of images processed at once")
+	_ = fs.Parse(args)
+
+	serviceCfg, err := service.InitConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	imageService, _, err := initService(*serviceCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	processed, err := imageService.GenerateRenditions(ctx, *all, *concurrency)
+	fmt.Printf("generated renditions of %v images\n", processed)
+
+	if err != nil {
+		log.Fatal(err) //nolint:gocritic // exit after defer is not important there
+	}
+}
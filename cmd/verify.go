@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/service"
+)
+
+// runVerify checks that files referenced by the database exist in the storage and are not corrupted.
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "remove broken files and download their images again")
+	_ = fs.Parse(args)
+
+	serviceCfg, err := service.InitConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	imageService, _, err := initService(*serviceCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	report, err := imageService.Verify(ctx, *repair)
+	if report != nil {
+		for _, p := range report.Problems {
+			fmt.Printf("%s %s %s: %s\n", p.Date.Format(time.DateOnly), p.Quality, p.Name, p.Problem)
+		}
+
+		for _, d := range report.Repaired {
+			fmt.Printf("repaired %s\n", d.Format(time.DateOnly))
+		}
+
+		fmt.Printf("checked %v files, %v problems, %v dates repaired\n",
+			report.Checked, len(report.Problems), len(report.Repaired))
+	}
+
+	if err != nil {
+		log.Fatal(err) //nolint:gocritic // exit after defer is not important there
+	}
+}
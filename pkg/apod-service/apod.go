@@ -3,105 +3,382 @@ package apod
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"mime"
 	"net/http"
 	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
 )
 
 type Service struct {
-	apiKey string
-}
+	baseURL   string
+	client    *http.Client
+	userAgent string
+
+	keys            *keyring
+	quality         models.QualityPolicy
+	retry           RetryPolicy
+	apiRetry        RetryPolicy
+	maxDownloadSize int64
 
-func NewService(apiKey string) *Service {
-	return &Service{apiKey: apiKey}
+	allowedContentTypes []string
 }
 
-type apodResponse struct {
-	URL string `json:"url"`
+// Config is a config of the APOD client.
+// Quality policy defines which renditions of the images are downloaded.
+// API keys are rotated when the current one is throttled.
+// MaxDownloadSize limits the size of the every downloaded file in bytes, zero means no limit.
+// Files are stored only if their content types detected by magic bytes are in AllowedContentTypes.
+type Config struct {
+	APIKeys             []string
+	Quality             models.QualityPolicy
+	Retry               RetryPolicy
+	MaxDownloadSize     int64
+	AllowedContentTypes []string
 }
 
-const errorStatusCode = 400
+func InitConfig() (*Config, error) {
+	quality, err := models.ParseQualityPolicy(os.Getenv("APOD_QUALITY"))
+	if err != nil {
+		return nil, err
+	}
 
-func (as *Service) getAPODForDate(ctx context.Context, date time.Time) (*apodResponse, error) {
-	urlA, err := url.ParseRequestURI("https://api.nasa.gov/planetary/apod")
+	retry, err := initRetryPolicy()
 	if err != nil {
-		return nil, fmt.Errorf("parse url: %w", err)
+		return nil, err
 	}
 
-	values := urlA.Query()
-	values.Set("api_key", as.apiKey)
-	values.Set("date", date.Format(time.DateOnly))
-	urlA.RawQuery = values.Encode()
+	keys := strings.Split(os.Getenv("NASA_API_KEYS"), ",")
+	if os.Getenv("NASA_API_KEYS") == "" {
+		keys = []string{os.Getenv("NASA_API_KEY")}
+	}
+
+	for i := range keys {
+		keys[i] = strings.TrimSpace(keys[i])
+	}
+
+	maxSize := int64(defaultMaxDownloadSize)
+	if v := os.Getenv("MAX_DOWNLOAD_SIZE"); v != "" {
+		maxSize, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cant parse max download size %q: %w", v, err)
+		}
+	}
+
+	allowed, err := initAllowedContentTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		APIKeys:             keys,
+		Quality:             quality,
+		Retry:               retry,
+		MaxDownloadSize:     maxSize,
+		AllowedContentTypes: allowed,
+	}, nil
+}
+
+// NewService is a constructor to the Service.
+// By default requests are sent to the api.nasa.gov with one minute timeout, it can be changed by options.
+// If no content types are allowed by the config, DefaultAllowedContentTypes are used.
+func NewService(cfg Config, opts ...Option) *Service {
+	o := newOptions(opts)
+
+	if len(cfg.AllowedContentTypes) == 0 {
+		cfg.AllowedContentTypes = DefaultAllowedContentTypes()
+	}
+
+	return &Service{
+		baseURL:   o.baseURL,
+		client:    o.client,
+		userAgent: o.userAgent,
+		keys:      newKeyring(cfg.APIKeys),
+		quality:   cfg.Quality,
+		retry:     cfg.Retry,
+		apiRetry:  cfg.Retry.without(http.StatusTooManyRequests),
+
+		maxDownloadSize:     cfg.MaxDownloadSize,
+		allowedContentTypes: cfg.AllowedContentTypes,
+	}
+}
+
+type apodResponse struct {
+	Date           string `json:"date"`
+	Title          string `json:"title"`
+	Explanation    string `json:"explanation"`
+	Copyright      string `json:"copyright"`
+	MediaType      string `json:"media_type"`
+	ServiceVersion string `json:"service_version"`
+	HDURL          string `json:"hdurl"`
+	URL            string `json:"url"`
+	ThumbnailURL   string `json:"thumbnail_url"`
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlA.String(), nil)
+func (ar *apodResponse) toModel() (*models.APOD, error) {
+	date, err := time.Parse(time.DateOnly, ar.Date)
 	if err != nil {
-		return nil, fmt.Errorf("new request: %w", err)
+		return nil, fmt.Errorf("parse date %q: %w", ar.Date, err)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	return &models.APOD{
+		Date:           date,
+		Title:          ar.Title,
+		Explanation:    ar.Explanation,
+		Copyright:      ar.Copyright,
+		MediaType:      ar.MediaType,
+		ServiceVersion: ar.ServiceVersion,
+		HDURL:          ar.HDURL,
+		OriginalURL:    ar.URL,
+		ThumbnailURL:   ar.ThumbnailURL,
+	}, nil
+}
+
+const (
+	errorStatusCode = 400
+
+	defaultMaxDownloadSize = 50 << 20
+)
+
+// callAPI makes request to the APOD api with the provided query values
+// and unmarshals the response into v.
+// If the api key is throttled the request is repeated with the next one,
+// if all keys are throttled *models.RateLimitError is returned.
+func (as *Service) callAPI(ctx context.Context, values url.Values, v any) error {
+	resp, err := as.doAPIRequest(ctx, values)
 	if err != nil {
-		return nil, fmt.Errorf("do request %q: %w", req.RequestURI, err)
+		return err
 	}
 	defer resp.Body.Close()
 
 	bts, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read body: %w", err)
+		return fmt.Errorf("read body: %w", err)
 	}
 
-	if resp.StatusCode >= errorStatusCode {
-		return nil, fmt.Errorf("status code %v, body %q", resp.StatusCode, string(bts))
+	err = json.Unmarshal(bts, v)
+	if err != nil {
+		return fmt.Errorf("unmarshal %q: %w", string(bts), err)
 	}
 
-	var apodResp apodResponse
+	return nil
+}
 
-	err = json.Unmarshal(bts, &apodResp)
+func (as *Service) doAPIRequest(ctx context.Context, values url.Values) (*http.Response, error) {
+	urlA, err := url.ParseRequestURI(as.baseURL + apodPath)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshal %q: %w", string(bts), err)
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+
+	values.Set("thumbs", "true")
+
+	for {
+		key, err := as.keys.pick()
+		if err != nil {
+			return nil, err
+		}
+
+		values.Set("api_key", key)
+		urlA.RawQuery = values.Encode()
+
+		resp, err := as.doWithRetry(ctx, &as.apiRetry, func() (*http.Request, error) {
+			return http.NewRequestWithContext(ctx, http.MethodGet, urlA.String(), nil)
+		})
+
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests {
+			as.keys.exhaust(key, statusErr.RetryAfter)
+
+			continue
+		}
+
+		if err != nil {
+			return nil, apiError(err)
+		}
+
+		as.keys.update(key, resp.Header)
+
+		return resp, nil
+	}
+}
+
+func (as *Service) getAPODForDate(ctx context.Context, date time.Time) (*apodResponse, error) {
+	values := url.Values{}
+	values.Set("date", date.Format(time.DateOnly))
+
+	var apodResp apodResponse
+
+	if err := as.callAPI(ctx, values, &apodResp); err != nil {
+		return nil, err
 	}
 
 	return &apodResp, nil
 }
 
-func (as *Service) getFile(ctx context.Context, url string) ([]byte, string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// GetAPODsForRange returns descriptions of all APOD entries between start and end dates inclusive
+// using a single request to the APOD api.
+func (as *Service) GetAPODsForRange(ctx context.Context, start, end time.Time) ([]models.APOD, error) {
+	values := url.Values{}
+	values.Set("start_date", start.Format(time.DateOnly))
+	values.Set("end_date", end.Format(time.DateOnly))
+
+	var apodResps []apodResponse
+
+	if err := as.callAPI(ctx, values, &apodResps); err != nil {
+		return nil, err
+	}
+
+	return toModels(apodResps)
+}
+
+// GetRandomAPODs returns descriptions of count random APOD entries
+// using the count mode of the APOD api.
+func (as *Service) GetRandomAPODs(ctx context.Context, count int) ([]models.APOD, error) {
+	values := url.Values{}
+	values.Set("count", strconv.Itoa(count))
+
+	var apodResps []apodResponse
+
+	if err := as.callAPI(ctx, values, &apodResps); err != nil {
+		return nil, err
+	}
+
+	return toModels(apodResps)
+}
+
+func toModels(apodResps []apodResponse) ([]models.APOD, error) {
+	apods := make([]models.APOD, 0, len(apodResps))
+
+	for i := range apodResps {
+		apod, err := apodResps[i].toModel()
+		if err != nil {
+			return nil, err
+		}
+
+		apods = append(apods, *apod)
+	}
+
+	return apods, nil
+}
+
+// openFile starts downloading of the file, the caller should close the returned body.
+// Size of the file is -1 if the server did not provide it.
+// Body returns models.ErrFileTooLarge if the file is bigger than the maximum download size.
+func (as *Service) openFile(ctx context.Context, url string) (*models.Image, error) {
+	resp, err := as.doWithRetry(ctx, &as.retry, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	})
+	if err != nil {
+		return nil, upstreamError(err)
+	}
+
+	if as.maxDownloadSize > 0 && resp.ContentLength > as.maxDownloadSize {
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("%w: content length %v, maximum %v", models.ErrFileTooLarge, resp.ContentLength, as.maxDownloadSize)
+	}
+
+	body, contentType, err := as.sniffContentType(resp.Body, resp.Header.Get("Content-Type"))
 	if err != nil {
-		return nil, "", fmt.Errorf("new request: %w", err)
+		resp.Body.Close()
+
+		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	return &models.Image{
+		Body:        newLimitedReadCloser(body, as.maxDownloadSize),
+		Size:        resp.ContentLength,
+		ContentType: contentType,
+		Extension:   canonicalExtensions[contentType],
+	}, nil
+}
+
+// GetImageForDate fetches the APOD description of the provided date and downloads its images.
+// Every image is passed to the save function while it is being downloaded.
+// Images are downloaded according to the quality policy, if the entry has no HD image
+// the standard one is downloaded instead.
+// For the video entries the thumbnail of the video is downloaded as the standard image,
+// if the video has no thumbnail save is not called.
+func (as *Service) GetImageForDate(ctx context.Context, date time.Time, save models.SaveImageFunc) (*models.APOD, error) {
+	apodResp, err := as.getAPODForDate(ctx, date)
 	if err != nil {
-		return nil, "", fmt.Errorf("do request %q: %w", req.RequestURI, err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	image, err := io.ReadAll(resp.Body)
+	apod, err := apodResp.toModel()
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	contentType := resp.Header.Get("Content-Type")
-	ext, err := mime.ExtensionsByType(contentType)
+	err = as.GetImagesForAPOD(ctx, apod, save)
 	if err != nil {
-		return nil, "", fmt.Errorf("extensions by type: %w", err)
+		return nil, err
 	}
 
-	return image, ext[0], nil
+	return apod, nil
 }
 
-func (as *Service) GetImageForDate(ctx context.Context, date time.Time) ([]byte, string, error) {
-	apod, err := as.getAPODForDate(ctx, date)
+// GetImagesForAPOD downloads the images of the already fetched APOD entry
+// according to the quality policy and passes them to the save function.
+// Body of the image is valid only during the save call.
+func (as *Service) GetImagesForAPOD(ctx context.Context, apod *models.APOD, save models.SaveImageFunc) error {
+	urls, err := as.imageURLs(apod)
 	if err != nil {
-		return nil, "", err
+		return err
 	}
 
-	image, ext, err := as.getFile(ctx, apod.URL)
+	for quality, url := range urls {
+		if err := as.downloadImage(ctx, quality, url, save); err != nil {
+			return fmt.Errorf("get %s image: %w", quality, err)
+		}
+	}
+
+	return nil
+}
+
+func (as *Service) downloadImage(ctx context.Context, quality models.Quality, url string, save models.SaveImageFunc) error {
+	img, err := as.openFile(ctx, url)
 	if err != nil {
-		return nil, "", err
+		return err
 	}
+	defer img.Body.Close()
+
+	img.Quality = quality
 
-	return image, ext, nil
+	return save(ctx, img)
+}
+
+// imageURLs returns urls of the images which should be downloaded for the APOD entry.
+func (as *Service) imageURLs(apod *models.APOD) (map[models.Quality]string, error) {
+	switch apod.MediaType {
+	case models.MediaTypeVideo:
+		if apod.ThumbnailURL == "" {
+			return nil, nil
+		}
+
+		return map[models.Quality]string{models.QualityStandard: apod.ThumbnailURL}, nil
+	case models.MediaTypeImage:
+		urls := make(map[models.Quality]string)
+
+		for _, q := range as.quality.Qualities() {
+			switch {
+			case q == models.QualityStandard:
+				urls[models.QualityStandard] = apod.OriginalURL
+			case q == models.QualityHD && apod.HDURL != "":
+				urls[models.QualityHD] = apod.HDURL
+			default:
+				urls[models.QualityStandard] = apod.OriginalURL
+			}
+		}
+
+		return urls, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", models.ErrUnsupportedMediaType, apod.MediaType)
+	}
 }
@@ -0,0 +1,303 @@
+package apod_test
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	apod "github.com/Dyleme/apod.git/pkg/apod-service"
+	"github.com/Dyleme/apod.git/pkg/apod-service/apodtest"
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+const testMaxDownloadSize = 1 << 20
+
+var (
+	imageDate    = time.Date(2023, time.January, 10, 0, 0, 0, 0, time.UTC)
+	videoDate    = time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+	notFoundDate = time.Date(2023, time.February, 2, 0, 0, 0, 0, time.UTC)
+)
+
+// newService starts the fake api and returns the client pointed to it with short retry delays.
+func newService(t *testing.T, opts ...apod.Option) (*apod.Service, *apodtest.Server) {
+	t.Helper()
+
+	srv := apodtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	retry := apod.DefaultRetryPolicy()
+	retry.BaseDelay = time.Millisecond
+	retry.MaxDelay = 10 * time.Millisecond
+
+	cfg := apod.Config{
+		APIKeys:         []string{"key"},
+		Quality:         models.QualityPolicyStandard,
+		Retry:           retry,
+		MaxDownloadSize: testMaxDownloadSize,
+	}
+
+	return apod.NewService(cfg, append([]apod.Option{apod.WithBaseURL(srv.URL)}, opts...)...), srv
+}
+
+// readImages returns the save function which reads the images and records their qualities.
+func readImages(saved map[models.Quality]int) models.SaveImageFunc {
+	return func(_ context.Context, img *models.Image) error {
+		data, err := io.ReadAll(img.Body)
+		if err != nil {
+			return err
+		}
+
+		saved[img.Quality] = len(data)
+
+		return nil
+	}
+}
+
+func TestGetImageForDate(t *testing.T) {
+	as, _ := newService(t)
+
+	saved := make(map[models.Quality]int)
+
+	got, err := as.GetImageForDate(context.Background(), imageDate, readImages(saved))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.Date.Equal(imageDate) {
+		t.Errorf("date = %v, want %v", got.Date, imageDate)
+	}
+
+	if saved[models.QualityStandard] == 0 {
+		t.Errorf("standard image is not saved: %v", saved)
+	}
+}
+
+func TestGetImageForDateVideo(t *testing.T) {
+	as, _ := newService(t)
+
+	got, err := as.GetImageForDate(context.Background(), videoDate, readImages(make(map[models.Quality]int)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.MediaType != models.MediaTypeVideo {
+		t.Errorf("media type = %v, want %v", got.MediaType, models.MediaTypeVideo)
+	}
+}
+
+func TestGetImageForDateErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		date    time.Time
+		fixture *apodtest.ErrorEntry
+		want    error
+		notWant error
+	}{
+		{
+			name: "not found",
+			date: notFoundDate,
+			want: models.ErrAPODNotFound,
+		},
+		{
+			name:    "before the first date",
+			date:    apodtest.FirstDate.AddDate(0, 0, -1),
+			want:    models.ErrDateOutOfRange,
+			notWant: models.ErrUpstreamUnavailable,
+		},
+		{
+			name:    "bad request not about the date",
+			date:    imageDate,
+			fixture: &apodtest.ErrorEntry{StatusCode: http.StatusBadRequest, Message: "bad request"},
+			want:    models.ErrUpstreamUnavailable,
+			notWant: models.ErrDateOutOfRange,
+		},
+		{
+			name:    "invalid api key",
+			date:    imageDate,
+			fixture: &apodtest.ErrorEntry{StatusCode: http.StatusForbidden, Message: "API_KEY_INVALID"},
+			want:    models.ErrUpstreamUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			as, srv := newService(t)
+			if tc.fixture != nil {
+				srv.AddError(tc.date, *tc.fixture)
+			}
+
+			_, err := as.GetImageForDate(context.Background(), tc.date, readImages(make(map[models.Quality]int)))
+			if !errors.Is(err, tc.want) {
+				t.Errorf("error = %v, want %v", err, tc.want)
+			}
+
+			if tc.notWant != nil && errors.Is(err, tc.notWant) {
+				t.Errorf("error = %v, should not be %v", err, tc.notWant)
+			}
+		})
+	}
+}
+
+func TestRetryTransientFaults(t *testing.T) {
+	as, srv := newService(t)
+	srv.InjectFault(apodtest.Fault{Target: apodtest.TargetAPI, StatusCode: http.StatusServiceUnavailable, Times: 2})
+	srv.InjectFault(apodtest.Fault{Target: apodtest.TargetImages, StatusCode: http.StatusBadGateway, Times: 1})
+
+	_, err := as.GetImageForDate(context.Background(), imageDate, readImages(make(map[models.Quality]int)))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	as, srv := newService(t)
+	srv.InjectFault(apodtest.Fault{Target: apodtest.TargetAPI, StatusCode: http.StatusInternalServerError})
+
+	_, err := as.GetImageForDate(context.Background(), imageDate, readImages(make(map[models.Quality]int)))
+	if !errors.Is(err, apod.ErrRetriesExhausted) || !errors.Is(err, models.ErrUpstreamUnavailable) {
+		t.Errorf("error = %v, want %v and %v", err, apod.ErrRetriesExhausted, models.ErrUpstreamUnavailable)
+	}
+}
+
+func TestRetryAfterLongerThanMaxDelay(t *testing.T) {
+	as, srv := newService(t)
+	srv.InjectFault(apodtest.Fault{
+		Target:     apodtest.TargetImages,
+		StatusCode: http.StatusTooManyRequests,
+		RetryAfter: time.Hour,
+	})
+
+	_, err := as.GetImageForDate(context.Background(), imageDate, readImages(make(map[models.Quality]int)))
+
+	var rateLimitErr *models.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("error = %v, want %T", err, rateLimitErr)
+	}
+
+	if rateLimitErr.RetryAfter != time.Hour {
+		t.Errorf("retry after = %v, want %v", rateLimitErr.RetryAfter, time.Hour)
+	}
+}
+
+func TestRateLimitedKeys(t *testing.T) {
+	as, srv := newService(t)
+	srv.InjectFault(apodtest.Fault{
+		Target:     apodtest.TargetAPI,
+		StatusCode: http.StatusTooManyRequests,
+		RetryAfter: time.Minute,
+	})
+
+	_, err := as.GetImageForDate(context.Background(), imageDate, readImages(make(map[models.Quality]int)))
+	if !errors.Is(err, models.ErrRateLimited) {
+		t.Errorf("error = %v, want %v", err, models.ErrRateLimited)
+	}
+}
+
+func TestInjectedClientTimeout(t *testing.T) {
+	as, srv := newService(t, apod.WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}))
+	srv.InjectFault(apodtest.Fault{Target: apodtest.TargetAPI, Delay: time.Second})
+
+	start := time.Now()
+
+	_, err := as.GetImageForDate(context.Background(), imageDate, readImages(make(map[models.Quality]int)))
+	if err == nil {
+		t.Fatal("error is expected")
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request took %v, timeout of the injected client is not used", elapsed)
+	}
+}
+
+func TestGetAPODsForRange(t *testing.T) {
+	as, srv := newService(t)
+
+	start := time.Date(2023, time.January, 30, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2023, time.February, 3, 0, 0, 0, 0, time.UTC)
+
+	apods, err := as.GetAPODsForRange(context.Background(), start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the not found date is skipped by the api in the range mode.
+	if got, want := len(apods), 4; got != want {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+
+	if got, want := srv.Requests(), 1; got != want {
+		t.Errorf("requests = %v, want %v", got, want)
+	}
+}
+
+func TestGetRandomAPODs(t *testing.T) {
+	as, _ := newService(t)
+
+	apods, err := as.GetRandomAPODs(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(apods), 5; got != want {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+
+	for _, a := range apods {
+		if a.Date.Before(apodtest.FirstDate) {
+			t.Errorf("date %v is before the first date", a.Date)
+		}
+	}
+}
+
+func TestGetRandomAPODsInvalidCount(t *testing.T) {
+	as, _ := newService(t)
+
+	_, err := as.GetRandomAPODs(context.Background(), 101)
+	if err == nil || errors.Is(err, models.ErrDateOutOfRange) {
+		t.Errorf("error = %v, want the upstream error", err)
+	}
+}
+
+func TestTransportErrorHidesAPIKey(t *testing.T) {
+	srv := apodtest.NewServer()
+	srv.Close()
+
+	cfg := apod.Config{
+		APIKeys:         []string{"SECRETKEY123"},
+		Quality:         models.QualityPolicyStandard,
+		Retry:           apod.RetryPolicy{MaxAttempts: 1},
+		MaxDownloadSize: testMaxDownloadSize,
+	}
+	as := apod.NewService(cfg, apod.WithBaseURL(srv.URL))
+
+	_, err := as.GetImageForDate(context.Background(), imageDate, readImages(make(map[models.Quality]int)))
+	if !errors.Is(err, models.ErrUpstreamUnavailable) {
+		t.Fatalf("error = %v, want %v", err, models.ErrUpstreamUnavailable)
+	}
+
+	if strings.Contains(err.Error(), "SECRETKEY123") {
+		t.Errorf("error %q contains the api key", err)
+	}
+}
+
+func TestRateLimitMetricPerKey(t *testing.T) {
+	cfg := apod.Config{APIKeys: []string{"first-abcd", "second-abcd"}}
+	apod.NewService(cfg)
+
+	metric, ok := expvar.Get("nasa_api_rate_limit").(*expvar.Map)
+	if !ok {
+		t.Fatal("rate limit metric is not published")
+	}
+
+	for _, name := range []string{"0:...abcd.remaining", "1:...abcd.remaining"} {
+		if metric.Get(name) == nil {
+			t.Errorf("metric %q is not published", name)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package apodtest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Target is the part of the Server affected by the Fault.
+type Target int
+
+const (
+	// TargetAPI affects requests to the /planetary/apod.
+	TargetAPI Target = iota
+	// TargetImages affects requests of the images.
+	TargetImages
+)
+
+// Fault changes the responses of the Server.
+// If StatusCode is set the Server responds with it and Retry-After header if RetryAfter is set.
+// Otherwise Delay slows down writing of the response body
+// and ContentType overrides the Content-Type header of the response.
+// Times is the number of the affected requests, zero means every request.
+type Fault struct {
+	Target      Target
+	StatusCode  int
+	RetryAfter  time.Duration
+	Delay       time.Duration
+	ContentType string
+	Times       int
+}
+
+// InjectFault adds the fault, faults are applied in the order of injecting.
+func (s *Server) InjectFault(f Fault) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.faults = nil
+}
+
+// takeFault returns the first active fault of the target and decreases its counter.
+func (s *Server) takeFault(target Target) *Fault {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for i, f := range s.faults {
+		if f.Target != target {
+			continue
+		}
+
+		fault := *f
+
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+
+		return &fault
+	}
+
+	return nil
+}
+
+// applyFault applies the fault of the target to the response.
+// It returns true if the response is already written.
+func (s *Server) applyFault(w http.ResponseWriter, r *http.Request, target Target) (http.ResponseWriter, bool) {
+	f := s.takeFault(target)
+	if f == nil {
+		return w, false
+	}
+
+	if f.StatusCode != 0 {
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Seconds())))
+		}
+
+		writeJSON(w, f.StatusCode, errorResponse{Code: f.StatusCode, Msg: http.StatusText(f.StatusCode), ServiceVersion: "v1"})
+
+		return w, true
+	}
+
+	if f.ContentType != "" {
+		w.Header().Set("Content-Type", f.ContentType)
+	}
+
+	if f.Delay > 0 {
+		return &slowWriter{ResponseWriter: w, delay: f.Delay, done: r.Context().Done()}, false
+	}
+
+	return w, false
+}
+
+// slowWriter sends the headers immediately and delays the body.
+type slowWriter struct {
+	http.ResponseWriter
+	delay       time.Duration
+	done        <-chan struct{}
+	wroteHeader bool
+}
+
+func (sw *slowWriter) WriteHeader(statusCode int) {
+	sw.wroteHeader = true
+	sw.ResponseWriter.WriteHeader(statusCode)
+
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (sw *slowWriter) Write(b []byte) (int, error) {
+	if !sw.wroteHeader {
+		sw.WriteHeader(http.StatusOK)
+	}
+
+	timer := time.NewTimer(sw.delay)
+	select {
+	case <-timer.C:
+	case <-sw.done:
+		timer.Stop()
+
+		return 0, http.ErrHandlerTimeout
+	}
+
+	return sw.ResponseWriter.Write(b)
+}
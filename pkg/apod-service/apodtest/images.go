@@ -0,0 +1,69 @@
+package apodtest
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+)
+
+const (
+	imageSize   = 64
+	hdImageSize = 256
+	colorShift  = 8
+)
+
+// generateImage draws the gradient image for the name, the color depends on the name.
+// Names ending with _hd have bigger size, .png names are encoded as png, others as jpeg.
+func generateImage(name string) ([]byte, string, error) {
+	base := name
+	if i := strings.LastIndex(name, "."); i != -1 {
+		base = name[:i]
+	}
+
+	if base == "" {
+		return nil, "", fmt.Errorf("empty image name")
+	}
+
+	size := imageSize
+	if strings.HasSuffix(base, "_hd") {
+		size = hdImageSize
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(base))
+	seed := h.Sum32()
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			img.Set(x, y, color.RGBA{
+				R: uint8(seed) + uint8(x),
+				G: uint8(seed>>colorShift) + uint8(y),
+				B: uint8(seed >> (2 * colorShift)),
+				A: 0xff,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if strings.HasSuffix(name, ".png") {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", fmt.Errorf("encode png: %w", err)
+		}
+
+		return buf.Bytes(), "image/png", nil
+	}
+
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		return nil, "", fmt.Errorf("encode jpeg: %w", err)
+	}
+
+	return buf.Bytes(), "image/jpeg", nil
+}
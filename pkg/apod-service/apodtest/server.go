@@ -0,0 +1,433 @@
+// Package apodtest provides a fake NASA APOD api for tests and offline development.
+package apodtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+const (
+	apodPath   = "/planetary/apod"
+	imagesPath = "/images/"
+
+	rateLimit = 1000
+)
+
+// FirstDate is the date of the first APOD, earlier dates are rejected by the api.
+var FirstDate = models.FirstAPODDate
+
+// Entry is the APOD entry served by the Server.
+// Empty URL fields are filled with the links to the images served by the Server.
+type Entry struct {
+	Date           time.Time
+	Title          string
+	Explanation    string
+	Copyright      string
+	MediaType      string
+	ServiceVersion string
+	URL            string
+	HDURL          string
+	ThumbnailURL   string
+}
+
+// ErrorEntry makes the api respond with the error for the date.
+type ErrorEntry struct {
+	StatusCode int
+	Message    string
+}
+
+// Server is a fake of the APOD api.
+// It serves generated image entries for every date which has no fixture,
+// fixtures can be added by AddEntry and AddError methods.
+// Images are generated and served by the Server itself.
+type Server struct {
+	*httptest.Server
+
+	mx        sync.Mutex
+	entries   map[string]Entry
+	errors    map[string]ErrorEntry
+	faults    []*Fault
+	remaining int
+	requests  int
+	now       func() time.Time
+}
+
+// NewServer starts the fake APOD api with the default fixtures:
+// a video entry for 2023-02-01 and not found error for 2023-02-02.
+// The caller should call Close when finished.
+func NewServer() *Server {
+	s := newServer()
+	s.Server = httptest.NewServer(s.routes())
+	s.addDefaultFixtures()
+
+	return s
+}
+
+// NewUnstartedServer returns the fake APOD api which is not started yet, like httptest.NewUnstartedServer.
+func NewUnstartedServer() *Server {
+	s := newServer()
+	s.Server = httptest.NewUnstartedServer(s.routes())
+	s.addDefaultFixtures()
+
+	return s
+}
+
+func newServer() *Server {
+	return &Server{
+		entries:   make(map[string]Entry),
+		errors:    make(map[string]ErrorEntry),
+		remaining: rateLimit,
+		now:       time.Now,
+	}
+}
+
+func (s *Server) addDefaultFixtures() {
+	s.AddEntry(Entry{
+		Date:        time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC),
+		Title:       "Fake Video",
+		Explanation: "Video entry of the fake APOD api.",
+		MediaType:   "video",
+		URL:         "https://www.youtube.com/embed/fake",
+	})
+	s.AddError(time.Date(2023, time.February, 2, 0, 0, 0, 0, time.UTC), ErrorEntry{
+		StatusCode: http.StatusNotFound,
+		Message:    "No data available for date: 2023-02-02",
+	})
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(apodPath, s.handleAPOD)
+	mux.HandleFunc(imagesPath, s.handleImage)
+
+	return mux
+}
+
+// AddEntry adds the fixture entry, it replaces the generated entry for its date.
+func (s *Server) AddEntry(e Entry) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.entries[e.Date.Format(time.DateOnly)] = e
+	delete(s.errors, e.Date.Format(time.DateOnly))
+}
+
+// AddError makes the api respond with the error for the date.
+func (s *Server) AddError(date time.Time, e ErrorEntry) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.errors[date.Format(time.DateOnly)] = e
+	delete(s.entries, date.Format(time.DateOnly))
+}
+
+// Requests returns the number of the requests to the api, requests of the images are not counted.
+func (s *Server) Requests() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.requests
+}
+
+type apodResponse struct {
+	Date           string `json:"date"`
+	Title          string `json:"title"`
+	Explanation    string `json:"explanation"`
+	Copyright      string `json:"copyright,omitempty"`
+	MediaType      string `json:"media_type"`
+	ServiceVersion string `json:"service_version"`
+	URL            string `json:"url"`
+	HDURL          string `json:"hdurl,omitempty"`
+	ThumbnailURL   string `json:"thumbnail_url,omitempty"`
+}
+
+type errorResponse struct {
+	Code           int    `json:"code"`
+	Msg            string `json:"msg"`
+	ServiceVersion string `json:"service_version"`
+}
+
+func (s *Server) handleAPOD(w http.ResponseWriter, r *http.Request) {
+	w, handled := s.applyFault(w, r, TargetAPI)
+	if handled {
+		return
+	}
+
+	query := r.URL.Query()
+
+	if query.Get("api_key") == "" {
+		writeJSON(w, http.StatusForbidden, errorResponse{Code: http.StatusForbidden, Msg: "API_KEY_MISSING"})
+
+		return
+	}
+
+	if !s.takeQuota(w) {
+		writeJSON(w, http.StatusTooManyRequests, errorResponse{Code: http.StatusTooManyRequests, Msg: "OVER_RATE_LIMIT"})
+
+		return
+	}
+
+	thumbs, _ := strconv.ParseBool(query.Get("thumbs"))
+
+	switch {
+	case query.Get("count") != "":
+		s.handleCount(w, query.Get("count"), thumbs)
+	case query.Get("start_date") != "":
+		s.handleRange(w, query.Get("start_date"), query.Get("end_date"), thumbs)
+	default:
+		s.handleDate(w, query.Get("date"), thumbs)
+	}
+}
+
+func (s *Server) handleDate(w http.ResponseWriter, dateString string, thumbs bool) {
+	date := s.today()
+
+	if dateString != "" {
+		var err error
+
+		date, err = time.Parse(time.DateOnly, dateString)
+		if err != nil {
+			writeBadRequest(w, fmt.Sprintf("time data %q does not match format '%%Y-%%m-%%d'", dateString))
+
+			return
+		}
+	}
+
+	if msg, ok := s.checkDate(date); !ok {
+		writeBadRequest(w, msg)
+
+		return
+	}
+
+	resp, errEntry := s.response(date, thumbs)
+	if errEntry != nil {
+		writeJSON(w, errEntry.StatusCode, errorResponse{Code: errEntry.StatusCode, Msg: errEntry.Message, ServiceVersion: "v1"})
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleRange(w http.ResponseWriter, startString, endString string, thumbs bool) {
+	start, err := time.Parse(time.DateOnly, startString)
+	if err != nil {
+		writeBadRequest(w, fmt.Sprintf("time data %q does not match format '%%Y-%%m-%%d'", startString))
+
+		return
+	}
+
+	end := s.today()
+	if endString != "" {
+		end, err = time.Parse(time.DateOnly, endString)
+		if err != nil {
+			writeBadRequest(w, fmt.Sprintf("time data %q does not match format '%%Y-%%m-%%d'", endString))
+
+			return
+		}
+	}
+
+	for _, d := range []time.Time{start, end} {
+		if msg, ok := s.checkDate(d); !ok {
+			writeBadRequest(w, msg)
+
+			return
+		}
+	}
+
+	if end.Before(start) {
+		writeBadRequest(w, "start_date cannot be after end_date")
+
+		return
+	}
+
+	resps := make([]apodResponse, 0)
+
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		// the real api skips dates without data in the range mode.
+		if resp, errEntry := s.response(d, thumbs); errEntry == nil {
+			resps = append(resps, *resp)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resps)
+}
+
+func (s *Server) handleCount(w http.ResponseWriter, countString string, thumbs bool) {
+	const maxCount = 100
+
+	count, err := strconv.Atoi(countString)
+	if err != nil || count < 1 || count > maxCount {
+		writeBadRequest(w, fmt.Sprintf("count must be positive and cannot exceed %v", maxCount))
+
+		return
+	}
+
+	days := int(s.today().Sub(FirstDate).Hours()/24) + 1 //nolint:gomnd // hours in day
+	resps := make([]apodResponse, 0, count)
+
+	for len(resps) < count {
+		d := FirstDate.AddDate(0, 0, rand.Intn(days)) //nolint:gosec // random dates do not need secure random
+		if resp, errEntry := s.response(d, thumbs); errEntry == nil {
+			resps = append(resps, *resp)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resps)
+}
+
+// checkDate validates that date is in the range of the APOD dates.
+func (s *Server) checkDate(date time.Time) (string, bool) {
+	today := s.today()
+	if date.Before(FirstDate) || date.After(today) {
+		return fmt.Sprintf("Date must be between %s and %s.",
+			FirstDate.Format("Jan 2, 2006"), today.Format("Jan 2, 2006")), false
+	}
+
+	return "", true
+}
+
+func (s *Server) today() time.Time {
+	now := s.now().UTC()
+
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// response returns the entry for the date, fixture or generated one.
+func (s *Server) response(date time.Time, thumbs bool) (*apodResponse, *ErrorEntry) {
+	s.mx.Lock()
+	key := date.Format(time.DateOnly)
+	errEntry, isErr := s.errors[key]
+	entry, ok := s.entries[key]
+	s.mx.Unlock()
+
+	if isErr {
+		return nil, &errEntry
+	}
+
+	if !ok {
+		entry = Entry{
+			Date:        date,
+			Title:       "Fake APOD " + key,
+			Explanation: "Generated entry of the fake APOD api.",
+			Copyright:   "apodtest",
+			MediaType:   "image",
+		}
+	}
+
+	if entry.ServiceVersion == "" {
+		entry.ServiceVersion = "v1"
+	}
+
+	resp := apodResponse{
+		Date:           key,
+		Title:          entry.Title,
+		Explanation:    entry.Explanation,
+		Copyright:      entry.Copyright,
+		MediaType:      entry.MediaType,
+		ServiceVersion: entry.ServiceVersion,
+		URL:            entry.URL,
+		HDURL:          entry.HDURL,
+		ThumbnailURL:   entry.ThumbnailURL,
+	}
+
+	switch entry.MediaType {
+	case "video":
+		if thumbs && resp.ThumbnailURL == "" {
+			resp.ThumbnailURL = s.URL + imagesPath + key + "_thumb.jpg"
+		}
+
+		if !thumbs {
+			resp.ThumbnailURL = ""
+		}
+	case "image":
+		if resp.URL == "" {
+			resp.URL = s.URL + imagesPath + key + ".jpg"
+		}
+
+		if resp.HDURL == "" {
+			resp.HDURL = s.URL + imagesPath + key + "_hd.png"
+		}
+	}
+
+	return &resp, nil
+}
+
+// takeQuota decreases the remaining quota and sets rate limit headers.
+func (s *Server) takeQuota(w http.ResponseWriter) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.requests++
+
+	allowed := s.remaining > 0
+	if allowed {
+		s.remaining--
+	}
+
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rateLimit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.remaining))
+
+	return allowed
+}
+
+// SetRemaining sets the remaining quota of the api, when it is zero the api responds with 429.
+func (s *Server) SetRemaining(remaining int) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.remaining = remaining
+}
+
+func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
+	w, handled := s.applyFault(w, r, TargetImages)
+	if handled {
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, imagesPath)
+
+	data, contentType, err := generateImage(name)
+	if err != nil {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "" {
+		contentType = ct
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+func writeBadRequest(w http.ResponseWriter, msg string) {
+	writeJSON(w, http.StatusBadRequest, errorResponse{Code: http.StatusBadRequest, Msg: msg, ServiceVersion: "v1"})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	bts, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(bts)
+}
@@ -0,0 +1,46 @@
+package apod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// dateRangeMessage is the beginning of the message with which the api answers to dates out of the range of published entries.
+const dateRangeMessage = "Date must be between"
+
+// apiError wraps the error of the api request with the models error describing its reason.
+// The api answers with 400 for dates out of the range of published entries and with 404 for missing entries.
+// Other 400 responses are treated as upstream errors, 403 means the api key is invalid and is logged.
+func apiError(err error) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusBadRequest:
+			if strings.Contains(statusErr.Body, dateRangeMessage) {
+				return fmt.Errorf("%w: %w", models.ErrDateOutOfRange, err)
+			}
+		case http.StatusForbidden:
+			logrus.Errorf("apod api rejected the api key: %v", err)
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %w", models.ErrAPODNotFound, err)
+		}
+	}
+
+	return upstreamError(err)
+}
+
+// upstreamError wraps the error of the request to NASA with models.ErrUpstreamUnavailable.
+// Cancellation and rate limiting errors are returned as is.
+func upstreamError(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, models.ErrRateLimited) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", models.ErrUpstreamUnavailable, err)
+}
@@ -0,0 +1,139 @@
+package apod
+
+import (
+	"expvar"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+const (
+	// defaultKeyCooldown is the time after which the throttled key is used again
+	// if the response has no Retry-After header. NASA limits requests per hour.
+	defaultKeyCooldown = time.Hour
+
+	visibleKeySuffix = 4
+)
+
+// rateLimitMetric exposes the quota of the every api key.
+// Names of the metrics start with the index of the key, so the keys with the same suffix do not collide.
+var rateLimitMetric = expvar.NewMap("nasa_api_rate_limit")
+
+type apiKey struct {
+	key            string
+	remaining      *expvar.Int
+	limit          *expvar.Int
+	exhaustedUntil time.Time
+}
+
+// keyring tracks the quota of the api keys and rotates them when the current one is throttled.
+type keyring struct {
+	mx      sync.Mutex
+	keys    []*apiKey
+	current int
+}
+
+func newKeyring(keys []string) *keyring {
+	kr := &keyring{keys: make([]*apiKey, 0, len(keys))}
+
+	for i, k := range keys {
+		name := strconv.Itoa(i) + ":" + maskKey(k)
+		ak := &apiKey{key: k, remaining: new(expvar.Int), limit: new(expvar.Int)}
+		ak.remaining.Set(-1)
+		ak.limit.Set(-1)
+		rateLimitMetric.Set(name+".remaining", ak.remaining)
+		rateLimitMetric.Set(name+".limit", ak.limit)
+		kr.keys = append(kr.keys, ak)
+	}
+
+	return kr
+}
+
+// pick returns the first not exhausted key starting from the current one.
+// If every key is exhausted *models.RateLimitError is returned.
+func (kr *keyring) pick() (string, error) {
+	kr.mx.Lock()
+	defer kr.mx.Unlock()
+
+	if len(kr.keys) == 0 {
+		return "", &models.RateLimitError{}
+	}
+
+	now := time.Now()
+	earliest := kr.keys[0].exhaustedUntil
+
+	for i := 0; i < len(kr.keys); i++ {
+		idx := (kr.current + i) % len(kr.keys)
+		k := kr.keys[idx]
+
+		if !now.Before(k.exhaustedUntil) {
+			kr.current = idx
+
+			return k.key, nil
+		}
+
+		if k.exhaustedUntil.Before(earliest) {
+			earliest = k.exhaustedUntil
+		}
+	}
+
+	return "", &models.RateLimitError{RetryAfter: earliest.Sub(now)}
+}
+
+// exhaust marks the key as throttled for the retryAfter duration or for the default cooldown.
+func (kr *keyring) exhaust(key string, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = defaultKeyCooldown
+	}
+
+	kr.mx.Lock()
+	defer kr.mx.Unlock()
+
+	for _, k := range kr.keys {
+		if k.key == key {
+			k.exhaustedUntil = time.Now().Add(retryAfter)
+			k.remaining.Set(0)
+		}
+	}
+}
+
+// update stores the quota of the key from X-RateLimit headers of the response.
+// Key without remaining requests is marked as exhausted.
+func (kr *keyring) update(key string, header http.Header) {
+	remaining, err := strconv.ParseInt(header.Get("X-RateLimit-Remaining"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	limit, err := strconv.ParseInt(header.Get("X-RateLimit-Limit"), 10, 64)
+	if err != nil {
+		limit = -1
+	}
+
+	kr.mx.Lock()
+	for _, k := range kr.keys {
+		if k.key == key {
+			k.remaining.Set(remaining)
+			k.limit.Set(limit)
+		}
+	}
+	kr.mx.Unlock()
+
+	if remaining <= 0 {
+		kr.exhaust(key, 0)
+	}
+}
+
+// maskKey hides the key in metrics, only the last characters are visible.
+// Short keys show at most half of their characters, so no key is exposed completely.
+func maskKey(key string) string {
+	visible := visibleKeySuffix
+	if visible > len(key)/2 {
+		visible = len(key) / 2
+	}
+
+	return "..." + key[len(key)-visible:]
+}
@@ -0,0 +1,42 @@
+package apod
+
+import (
+	"io"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+// limitedReadCloser returns models.ErrFileTooLarge if more than limit bytes are read.
+type limitedReadCloser struct {
+	rc        io.ReadCloser
+	remaining int64
+}
+
+// newLimitedReadCloser limits the reader by limit bytes, if limit is not positive rc is returned.
+func newLimitedReadCloser(rc io.ReadCloser, limit int64) io.ReadCloser {
+	if limit <= 0 {
+		return rc
+	}
+
+	return &limitedReadCloser{rc: rc, remaining: limit}
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	// allow to read one byte more to distinguish the file of exactly limit size from the bigger one.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.rc.Read(p)
+	l.remaining -= int64(n)
+
+	if l.remaining < 0 {
+		return n, models.ErrFileTooLarge
+	}
+
+	return n, err
+}
+
+func (l *limitedReadCloser) Close() error {
+	return l.rc.Close()
+}
@@ -0,0 +1,90 @@
+package apod
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://api.nasa.gov"
+	defaultTimeout = time.Minute
+
+	apodPath = "/planetary/apod"
+)
+
+type options struct {
+	baseURL   string
+	client    *http.Client
+	transport http.RoundTripper
+	timeout   time.Duration
+	userAgent string
+}
+
+// Option configures the Service.
+type Option func(*options)
+
+// WithBaseURL sets the base URL of the NASA api, "/planetary/apod" is appended to it.
+// It is used to point the Service to the proxy or to the fake server.
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sets the client which is used for all requests.
+// The client is copied, so its Transport and Timeout can be overridden by other options, otherwise they are kept.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithTransport sets the transport of the http client.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithTimeout sets the timeout of the every request including reading of the response body.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the every request.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		baseURL: defaultBaseURL,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// the timeout of the injected client is kept unless it is set by WithTimeout.
+	client := http.Client{Timeout: defaultTimeout}
+	if o.client != nil {
+		client = *o.client
+	}
+
+	if o.transport != nil {
+		client.Transport = o.transport
+	}
+
+	if o.timeout > 0 {
+		client.Timeout = o.timeout
+	}
+
+	o.client = &client
+
+	return o
+}
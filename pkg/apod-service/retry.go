@@ -0,0 +1,275 @@
+package apod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+const (
+	defaultMaxAttempts = 4
+	defaultBaseDelay   = 500 * time.Millisecond
+	defaultMaxDelay    = 30 * time.Second
+
+	maxErrorBodyLen = 1 << 10
+)
+
+// ErrRetriesExhausted is returned when all attempts of the request failed with transient errors.
+var ErrRetriesExhausted = errors.New("retries exhausted")
+
+// StatusError is returned when the server responds with the error status code.
+// Errors with status codes which are not retried by the RetryPolicy are permanent.
+type StatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status code %v, body %q", e.StatusCode, e.Body)
+}
+
+// RetryPolicy defines how the failed requests are retried.
+// Delay between attempts grows exponentially from BaseDelay up to MaxDelay with the full jitter.
+// If the response has Retry-After header its value is used as the delay,
+// if it is longer than MaxDelay the request is not retried and *models.RateLimitError is returned.
+// Transport errors and responses with RetryStatusCodes are retried.
+type RetryPolicy struct {
+	MaxAttempts      int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	RetryStatusCodes []int
+}
+
+// DefaultRetryPolicy returns the policy which retries rate limiting and server errors.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: defaultMaxAttempts,
+		BaseDelay:   defaultBaseDelay,
+		MaxDelay:    defaultMaxDelay,
+		RetryStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// initRetryPolicy overrides values of the default policy with the values from environment.
+func initRetryPolicy() (RetryPolicy, error) {
+	policy := DefaultRetryPolicy()
+
+	if v := os.Getenv("NASA_RETRY_MAX_ATTEMPTS"); v != "" {
+		attempts, err := strconv.Atoi(v)
+		if err != nil {
+			return policy, fmt.Errorf("cant parse max attempts %q: %w", v, err)
+		}
+
+		policy.MaxAttempts = attempts
+	}
+
+	if v := os.Getenv("NASA_RETRY_BASE_DELAY"); v != "" {
+		delay, err := time.ParseDuration(v)
+		if err != nil {
+			return policy, fmt.Errorf("cant parse base delay %q: %w", v, err)
+		}
+
+		policy.BaseDelay = delay
+	}
+
+	if v := os.Getenv("NASA_RETRY_MAX_DELAY"); v != "" {
+		delay, err := time.ParseDuration(v)
+		if err != nil {
+			return policy, fmt.Errorf("cant parse max delay %q: %w", v, err)
+		}
+
+		policy.MaxDelay = delay
+	}
+
+	if v := os.Getenv("NASA_RETRY_STATUS_CODES"); v != "" {
+		codes := strings.Split(v, ",")
+		policy.RetryStatusCodes = make([]int, 0, len(codes))
+
+		for _, c := range codes {
+			code, err := strconv.Atoi(strings.TrimSpace(c))
+			if err != nil {
+				return policy, fmt.Errorf("cant parse status code %q: %w", c, err)
+			}
+
+			policy.RetryStatusCodes = append(policy.RetryStatusCodes, code)
+		}
+	}
+
+	return policy, nil
+}
+
+// without returns copy of the policy which does not retry provided status code.
+func (p RetryPolicy) without(statusCode int) RetryPolicy {
+	codes := make([]int, 0, len(p.RetryStatusCodes))
+
+	for _, c := range p.RetryStatusCodes {
+		if c != statusCode {
+			codes = append(codes, c)
+		}
+	}
+
+	p.RetryStatusCodes = codes
+
+	return p
+}
+
+func (p *RetryPolicy) isRetryable(statusCode int) bool {
+	for _, c := range p.RetryStatusCodes {
+		if c == statusCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff returns the delay before the next attempt, attempt starts from 1.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(delay) + 1)) //nolint:gosec // jitter does not need secure random
+}
+
+// doWithRetry performs the request retrying transient failures according to the policy.
+// The response is returned only for the successful status codes.
+// Non retryable status codes are returned as *StatusError,
+// Retry-After longer than MaxDelay is returned as *models.RateLimitError wrapping the *StatusError,
+// if all attempts failed the error wraps ErrRetriesExhausted and the last failure.
+func (as *Service) doWithRetry(
+	ctx context.Context,
+	policy *RetryPolicy,
+	newRequest func() (*http.Request, error),
+) (*http.Response, error) {
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, fmt.Errorf("new request: %w", err)
+		}
+
+		if as.userAgent != "" {
+			req.Header.Set("User-Agent", as.userAgent)
+		}
+
+		resp, err := as.client.Do(req)
+
+		var retryAfter time.Duration
+
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("do request %s%s: %w", req.URL.Host, req.URL.Path, ctx.Err())
+			}
+
+			lastErr = fmt.Errorf("do request %s%s: %w", req.URL.Host, req.URL.Path, withoutURL(err))
+		case resp.StatusCode >= errorStatusCode:
+			statusErr := readStatusError(resp)
+			if !policy.isRetryable(resp.StatusCode) {
+				return nil, statusErr
+			}
+
+			lastErr = statusErr
+			retryAfter = statusErr.RetryAfter
+		default:
+			return resp, nil
+		}
+
+		if retryAfter > policy.MaxDelay {
+			return nil, fmt.Errorf("%w: %w", &models.RateLimitError{RetryAfter: retryAfter}, lastErr)
+		}
+
+		if attempt == attempts {
+			break
+		}
+
+		delay := policy.backoff(attempt)
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil, fmt.Errorf("wait for retry: %w", ctx.Err())
+		}
+	}
+
+	return nil, fmt.Errorf("%w after %v attempts: %w", ErrRetriesExhausted, attempts, lastErr)
+}
+
+// withoutURL removes the url from the transport error, because the query of the url has the api key.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+
+	return err
+}
+
+// readStatusError reads the body of the failed response and closes it.
+func readStatusError(resp *http.Response) *StatusError {
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLen))
+
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter parses value of the Retry-After header which can be seconds or http date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+
+	return 0
+}
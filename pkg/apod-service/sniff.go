@@ -0,0 +1,89 @@
+package apod
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+// sniffLen is the number of bytes used by http.DetectContentType.
+const sniffLen = 512
+
+// canonicalExtensions maps allowed content types to the extensions of the stored files.
+var canonicalExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// DefaultAllowedContentTypes returns all content types which can be stored.
+func DefaultAllowedContentTypes() []string {
+	return []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+}
+
+// initAllowedContentTypes reads allowed content types from the environment.
+func initAllowedContentTypes() ([]string, error) {
+	v := os.Getenv("ALLOWED_CONTENT_TYPES")
+	if v == "" {
+		return DefaultAllowedContentTypes(), nil
+	}
+
+	types := strings.Split(v, ",")
+	for i := range types {
+		types[i] = strings.TrimSpace(types[i])
+		if _, ok := canonicalExtensions[types[i]]; !ok {
+			return nil, fmt.Errorf("content type %q can not be allowed", types[i])
+		}
+	}
+
+	return types, nil
+}
+
+// sniffedBody reads the sniffed bytes before the rest of the body.
+type sniffedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// sniffContentType detects the content type of the body by its magic bytes
+// and checks it against the allowed content types.
+// Declared content type of the response is used only in the error message,
+// because servers often send wrong or missing Content-Type.
+// It returns the body which still contains the sniffed bytes and the detected content type.
+func (as *Service) sniffContentType(body io.ReadCloser, declared string) (io.ReadCloser, string, error) {
+	br := bufio.NewReaderSize(body, sniffLen)
+
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", fmt.Errorf("peek body: %w", err)
+	}
+
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return nil, "", fmt.Errorf("parse detected content type: %w", err)
+	}
+
+	if !as.isAllowed(detected) {
+		return nil, "", &models.ContentTypeError{Declared: declared, Detected: detected}
+	}
+
+	return sniffedBody{Reader: br, Closer: body}, detected, nil
+}
+
+func (as *Service) isAllowed(contentType string) bool {
+	for _, t := range as.allowedContentTypes {
+		if t == contentType {
+			return true
+		}
+	}
+
+	return false
+}
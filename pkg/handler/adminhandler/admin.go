@@ -0,0 +1,70 @@
+// Package adminhandler serves the administrative endpoints, every request requires the bearer token.
+package adminhandler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/handler/response"
+	"github.com/Dyleme/apod.git/pkg/models"
+	"github.com/go-chi/chi/v5"
+)
+
+var errUnauthorized = errors.New("invalid admin token")
+
+type Service interface {
+	ClearFailure(ctx context.Context, date time.Time) error
+}
+
+type Handler struct {
+	service Service
+	token   string
+}
+
+// New returns the handler which accepts requests with the "Authorization: Bearer <token>" header.
+func New(service Service, token string) *Handler {
+	return &Handler{service: service, token: token}
+}
+
+// ClearFailure removes the recorded failed download of the date, so it is downloaded on the next request.
+func (ah *Handler) ClearFailure(w http.ResponseWriter, r *http.Request) {
+	date, err := time.Parse(time.DateOnly, chi.URLParam(r, "date"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeBadRequest, err)
+
+		return
+	}
+
+	err = ah.service.ClearFailure(r.Context(), date)
+	if errors.Is(err, models.ErrFailureNotExists) {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, err)
+
+		return
+	}
+
+	if err != nil {
+		response.Internal(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Authorize passes to next only the requests with the admin token.
+func (ah *Handler) Authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(ah.token)) != 1 {
+			response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, errUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,34 +1,77 @@
 package handler
 
 import (
+	"expvar"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 // Handler is a struct which has service interfaces.
 type Handler struct {
 	imagesHandler ImagesHandler
+	mediaHandler  MediaHandler
+	adminHandler  AdminHandler
+	filesHandler  http.Handler
 }
 
 // This constructor initialize Handler's fields with provided arguments.
-func New(imagesHandler ImagesHandler) *Handler {
+// mediaHandler serves images at /media/{date}, the route is not registered if it is nil.
+// adminHandler serves the administrative endpoints and the metrics at /admin/, they are not registered if it is nil.
+// filesHandler serves stored files at /files/, it can be nil if files are served by the storage.
+func New(imagesHandler ImagesHandler, mediaHandler MediaHandler, adminHandler AdminHandler, filesHandler http.Handler) *Handler {
 	return &Handler{
 		imagesHandler: imagesHandler,
+		mediaHandler:  mediaHandler,
+		adminHandler:  adminHandler,
+		filesHandler:  filesHandler,
 	}
 }
 
 type ImagesHandler interface {
 	GetForDate(w http.ResponseWriter, r *http.Request)
+	GetToday(w http.ResponseWriter, r *http.Request)
+	GetRandomImages(w http.ResponseWriter, r *http.Request)
 	GetAlbumImages(w http.ResponseWriter, r *http.Request)
 }
 
+type MediaHandler interface {
+	GetMedia(w http.ResponseWriter, r *http.Request)
+}
+
+// AdminHandler serves the administrative endpoints, Authorize rejects requests without the admin credentials.
+type AdminHandler interface {
+	Authorize(next http.Handler) http.Handler
+	ClearFailure(w http.ResponseWriter, r *http.Request)
+}
+
 // InitRouters() method is used to initialize all endopoints with the routers.
 func (h *Handler) InitRouters() *chi.Mux {
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 
+	r.Get("/images/today", h.imagesHandler.GetToday)
+	r.Get("/images/random", h.imagesHandler.GetRandomImages)
 	r.Get("/images/{date}", h.imagesHandler.GetForDate)
 	r.Get("/images", h.imagesHandler.GetAlbumImages)
 
+	if h.mediaHandler != nil {
+		r.Get("/media/{date}", h.mediaHandler.GetMedia)
+		r.Head("/media/{date}", h.mediaHandler.GetMedia)
+	}
+
+	if h.adminHandler != nil {
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(h.adminHandler.Authorize)
+			r.Delete("/failures/{date}", h.adminHandler.ClearFailure)
+			r.Handle("/debug/vars", expvar.Handler())
+		})
+	}
+
+	if h.filesHandler != nil {
+		r.Handle("/files/*", http.StripPrefix("/files", h.filesHandler))
+	}
+
 	return r
 }
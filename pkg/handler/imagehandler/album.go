@@ -0,0 +1,128 @@
+package imagehandler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+const (
+	maxAlbumLimit = 200
+
+	cursorAfter  = "after"
+	cursorBefore = "before"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// albumResponse is the page of the album.
+type albumResponse struct {
+	Images     []imageResponse `json:"images"`
+	Total      int             `json:"total"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	PrevCursor string          `json:"prev_cursor,omitempty"`
+}
+
+func newAlbumResponse(page *models.AlbumPage, quality models.Quality) albumResponse {
+	resp := albumResponse{
+		Images:     make([]imageResponse, 0, len(page.Records)),
+		Total:      page.Total,
+		NextCursor: encodeCursor(page.Next),
+		PrevCursor: encodeCursor(page.Prev),
+	}
+
+	for i := range page.Records {
+		resp.Images = append(resp.Images, newImageResponse(&page.Records[i], quality))
+	}
+
+	return resp
+}
+
+// parseAlbumQuery parses query parameters from, to, sort, limit and cursor of the album request.
+func parseAlbumQuery(values url.Values) (models.AlbumQuery, error) {
+	// zero limit is replaced by the default of the service.
+	query := models.AlbumQuery{Descending: true}
+
+	var err error
+
+	if v := values.Get("from"); v != "" {
+		query.From, err = time.Parse(time.DateOnly, v)
+		if err != nil {
+			return query, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+
+	if v := values.Get("to"); v != "" {
+		query.To, err = time.Parse(time.DateOnly, v)
+		if err != nil {
+			return query, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+
+	if !query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From) {
+		return query, fmt.Errorf("from %v is after to %v", values.Get("from"), values.Get("to"))
+	}
+
+	switch v := values.Get("sort"); v {
+	case "", "desc":
+	case "asc":
+		query.Descending = false
+	default:
+		return query, fmt.Errorf("invalid sort %q, should be asc or desc", v)
+	}
+
+	if v := values.Get("limit"); v != "" {
+		query.Limit, err = strconv.Atoi(v)
+		if err != nil || query.Limit < 1 || query.Limit > maxAlbumLimit {
+			return query, fmt.Errorf("invalid limit %q, should be between 1 and %v", v, maxAlbumLimit)
+		}
+	}
+
+	if v := values.Get("cursor"); v != "" {
+		query.Cursor, err = decodeCursor(v)
+		if err != nil {
+			return query, err
+		}
+	}
+
+	return query, nil
+}
+
+// encodeCursor returns the opaque representation of the cursor, nil cursor is encoded to the empty string.
+func encodeCursor(c *models.AlbumCursor) string {
+	if c == nil {
+		return ""
+	}
+
+	direction := cursorAfter
+	if c.Before {
+		direction = cursorBefore
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(direction + ":" + c.Date.Format(time.DateOnly)))
+}
+
+func decodeCursor(s string) (*models.AlbumCursor, error) {
+	bts, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	direction, dateString, ok := strings.Cut(string(bts), ":")
+	if !ok || (direction != cursorAfter && direction != cursorBefore) {
+		return nil, errInvalidCursor
+	}
+
+	date, err := time.Parse(time.DateOnly, dateString)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return &models.AlbumCursor{Date: date, Before: direction == cursorBefore}, nil
+}
@@ -2,17 +2,20 @@ package imagehandler
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/Dyleme/apod.git/pkg/handler/response"
 	"github.com/Dyleme/apod.git/pkg/models"
 	"github.com/go-chi/chi/v5"
 )
 
 type Service interface {
-	GetImageURLForDate(ctx context.Context, date time.Time) (string, error)
-	GetAlbum(ctx context.Context) ([]models.AlbumRecord, error)
+	GetImageForDate(ctx context.Context, date time.Time) (*models.AlbumRecord, error)
+	GetTodayImage(ctx context.Context) (*models.AlbumRecord, error)
+	GetAlbum(ctx context.Context, query models.AlbumQuery) (*models.AlbumPage, error)
+	GetRandomImages(ctx context.Context, query models.RandomQuery) ([]models.AlbumRecord, error)
+	OpenImage(ctx context.Context, date time.Time, quality models.Quality, width int) (*models.MediaFile, error)
 }
 
 type Handler struct {
@@ -23,57 +26,149 @@ func New(imageService Service) *Handler {
 	return &Handler{service: imageService}
 }
 
-type urlResponse struct {
-	URL string `json:"url"`
+// imageResponse is the response for the APOD entry.
+// MediaType tells the kind of the entry: images have URL of the stored image,
+// videos have VideoURL and URL of the stored thumbnail if the video has one.
+type imageResponse struct {
+	Date           string `json:"date"`
+	URL            string `json:"url,omitempty"`
+	VideoURL       string `json:"video_url,omitempty"`
+	ThumbnailURL   string `json:"thumbnail_url,omitempty"`
+	Title          string `json:"title"`
+	Explanation    string `json:"explanation"`
+	Copyright      string `json:"copyright,omitempty"`
+	MediaType      string `json:"media_type"`
+	ServiceVersion string `json:"service_version"`
+	HDURL          string `json:"hdurl,omitempty"`
+	OriginalURL    string `json:"original_url"`
+
+	Renditions []renditionResponse `json:"renditions,omitempty"`
 }
 
-type albumRecordResponse struct {
-	Date string `json:"date"`
-	URL  string `json:"url"`
+// renditionResponse is the resized copy of the image.
+type renditionResponse struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
 }
 
+func newRenditionsResponse(renditions []models.Rendition) []renditionResponse {
+	if len(renditions) == 0 {
+		return nil
+	}
+
+	resp := make([]renditionResponse, 0, len(renditions))
+	for _, r := range renditions {
+		resp = append(resp, renditionResponse{URL: r.URL, Width: r.Width, Height: r.Height})
+	}
+
+	return resp
+}
+
+func newImageResponse(record *models.AlbumRecord, quality models.Quality) imageResponse {
+	if record.IsVideo() {
+		return imageResponse{
+			Date:           record.Date.Format(time.DateOnly),
+			VideoURL:       record.OriginalURL,
+			ThumbnailURL:   record.ImageURL(quality),
+			Title:          record.Title,
+			Explanation:    record.Explanation,
+			Copyright:      record.Copyright,
+			MediaType:      record.MediaType,
+			ServiceVersion: record.ServiceVersion,
+			OriginalURL:    record.OriginalURL,
+			Renditions:     newRenditionsResponse(record.Renditions),
+		}
+	}
+
+	return imageResponse{
+		Date:           record.Date.Format(time.DateOnly),
+		URL:            record.ImageURL(quality),
+		Title:          record.Title,
+		Explanation:    record.Explanation,
+		Copyright:      record.Copyright,
+		MediaType:      record.MediaType,
+		ServiceVersion: record.ServiceVersion,
+		HDURL:          record.HDURL,
+		OriginalURL:    record.OriginalURL,
+		Renditions:     newRenditionsResponse(record.Renditions),
+	}
+}
+
+// GetForDate returns the APOD entry for the date.
+// Query parameter quality selects the rendition of the image, standard or hd.
 func (ih *Handler) GetForDate(w http.ResponseWriter, r *http.Request) {
 	dateString := chi.URLParam(r, "date")
 
+	quality, err := models.ParseQuality(r.URL.Query().Get("quality"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeBadRequest, err)
+
+		return
+	}
+
 	date, err := time.Parse(time.DateOnly, dateString)
 	if err != nil {
-		responseError(w, err, http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeBadRequest, err)
 
 		return
 	}
 
-	if date.After(time.Now().UTC()) {
-		err = fmt.Errorf("provided date %q is in future", dateString)
-		responseError(w, err, http.StatusBadRequest)
+	image, err := ih.service.GetImageForDate(r.Context(), date)
+	if err != nil {
+		responseServiceError(w, r, err)
+
+		return
+	}
+
+	responseJSON(w, r, newImageResponse(image, quality))
+}
+
+// GetToday returns the APOD entry of the current day in the publication timezone of APOD.
+// Query parameter quality selects the rendition of the image, standard or hd.
+func (ih *Handler) GetToday(w http.ResponseWriter, r *http.Request) {
+	quality, err := models.ParseQuality(r.URL.Query().Get("quality"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeBadRequest, err)
 
 		return
 	}
 
-	url, err := ih.service.GetImageURLForDate(r.Context(), date)
+	image, err := ih.service.GetTodayImage(r.Context())
 	if err != nil {
-		responseError(w, err, http.StatusInternalServerError)
+		responseServiceError(w, r, err)
 
 		return
 	}
 
-	responseJSON(w, urlResponse{URL: url})
+	responseJSON(w, r, newImageResponse(image, quality))
 }
 
+// GetAlbumImages returns the page of the stored APOD entries.
+// Query parameter quality selects the rendition of the images, standard or hd.
+// Parameters from and to filter the dates, sort is asc or desc, newest entries are the first by default.
+// Next and previous pages are requested by the cursor from the response.
 func (ih *Handler) GetAlbumImages(w http.ResponseWriter, r *http.Request) {
-	urls, err := ih.service.GetAlbum(r.Context())
+	quality, err := models.ParseQuality(r.URL.Query().Get("quality"))
 	if err != nil {
-		responseError(w, err, http.StatusInternalServerError)
+		response.Error(w, r, http.StatusBadRequest, response.CodeBadRequest, err)
 
 		return
 	}
 
-	urlsResponse := make([]albumRecordResponse, 0, len(urls))
+	query, err := parseAlbumQuery(r.URL.Query())
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeBadRequest, err)
+
+		return
+	}
 
-	fmt.Println(urlsResponse)
-	for _, u := range urls {
-		urlsResponse = append(urlsResponse, albumRecordResponse{URL: u.URL, Date: u.Date.Format(time.DateOnly)})
+	page, err := ih.service.GetAlbum(r.Context(), query)
+	if err != nil {
+		responseServiceError(w, r, err)
+
+		return
 	}
 
-	fmt.Println(urlsResponse)
-	responseJSON(w, urlsResponse)
+	responseJSON(w, r, newAlbumResponse(page, quality))
 }
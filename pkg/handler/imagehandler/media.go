@@ -0,0 +1,90 @@
+package imagehandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/handler/response"
+	"github.com/Dyleme/apod.git/pkg/models"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// mediaCacheControl allows clients and proxies to cache images forever, images of the date never change.
+	mediaCacheControl = "public, max-age=31536000, immutable"
+
+	// mediaWriteTimeout replaces the write timeout of the server for the media responses,
+	// HD images are large and are streamed slowly to slow clients.
+	mediaWriteTimeout = 10 * time.Minute
+)
+
+// GetMedia streams the stored image of the date.
+// Query parameter quality selects the rendition of the image, standard or hd,
+// width selects the resized copy of the image instead.
+// Range, If-None-Match and If-Modified-Since requests are supported.
+func (ih *Handler) GetMedia(w http.ResponseWriter, r *http.Request) {
+	dateString := chi.URLParam(r, "date")
+
+	quality, err := models.ParseQuality(r.URL.Query().Get("quality"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeBadRequest, err)
+
+		return
+	}
+
+	var width int
+	if v := r.URL.Query().Get("width"); v != "" {
+		width, err = strconv.Atoi(v)
+		if err != nil || width <= 0 {
+			response.Error(w, r, http.StatusBadRequest, response.CodeBadRequest, fmt.Errorf("invalid width %q", v))
+
+			return
+		}
+	}
+
+	date, err := time.Parse(time.DateOnly, dateString)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeBadRequest, err)
+
+		return
+	}
+
+	file, err := ih.service.OpenImage(r.Context(), date, quality, width)
+	if errors.Is(err, models.ErrFileNotExists) {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, fmt.Errorf("no image for date %q", dateString))
+
+		return
+	}
+
+	if err != nil {
+		responseServiceError(w, r, err)
+
+		return
+	}
+	defer file.Content.Close()
+
+	// writers which do not support deadlines are not limited by the server timeout either.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(mediaWriteTimeout))
+
+	w.Header().Set("Cache-Control", mediaCacheControl)
+	w.Header().Set("ETag", mediaETag(file.Info))
+
+	if file.Info.ContentType != "" {
+		w.Header().Set("Content-Type", file.Info.ContentType)
+	}
+
+	http.ServeContent(w, r, file.Info.Name, file.Info.LastModified, file.Content)
+}
+
+// mediaETag returns the strong etag of the content hash,
+// files stored without the hash get the weak etag of their size and modification time.
+func mediaETag(info models.FileInfo) string {
+	if info.SHA256 != "" {
+		return strconv.Quote(info.SHA256)
+	}
+
+	return "W/" + strconv.Quote(strconv.FormatInt(info.Size, 16)+"-"+strconv.FormatInt(info.LastModified.Unix(), 16))
+}
@@ -0,0 +1,115 @@
+package imagehandler
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Dyleme/apod.git/pkg/handler/response"
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+const (
+	defaultRandomCount = 1
+	maxRandomCount     = 100
+
+	sourceStored = "stored"
+	sourceNASA   = "nasa"
+)
+
+// randomResponse is the list of random entries.
+// Seed is the seed of the picks of the stored entries, requests with it return the same entries.
+type randomResponse struct {
+	Images []imageResponse `json:"images"`
+	Seed   *int64          `json:"seed,omitempty"`
+}
+
+func newRandomResponse(records []models.AlbumRecord, query models.RandomQuery, quality models.Quality) randomResponse {
+	resp := randomResponse{Images: make([]imageResponse, 0, len(records))}
+
+	if !query.Fresh {
+		resp.Seed = &query.Seed
+	}
+
+	for i := range records {
+		resp.Images = append(resp.Images, newImageResponse(&records[i], quality))
+	}
+
+	return resp
+}
+
+// GetRandomImages returns random APOD entries.
+// Query parameter count is the number of entries, entries are picked from the stored ones
+// by the random generator with the seed parameter, a new seed is generated if it is not provided.
+// With source=nasa up to 10 random entries are fetched from NASA and stored instead, if it is enabled,
+// seed can not be used with it.
+// Query parameter quality selects the rendition of the images, standard or hd.
+func (ih *Handler) GetRandomImages(w http.ResponseWriter, r *http.Request) {
+	quality, err := models.ParseQuality(r.URL.Query().Get("quality"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeBadRequest, err)
+
+		return
+	}
+
+	query, err := parseRandomQuery(r.URL.Query())
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeBadRequest, err)
+
+		return
+	}
+
+	records, err := ih.service.GetRandomImages(r.Context(), query)
+	if err != nil {
+		responseServiceError(w, r, err)
+
+		return
+	}
+
+	responseJSON(w, r, newRandomResponse(records, query, quality))
+}
+
+// parseRandomQuery parses query parameters count, seed and source of the random request.
+func parseRandomQuery(values url.Values) (models.RandomQuery, error) {
+	query := models.RandomQuery{Count: defaultRandomCount}
+
+	var err error
+
+	switch v := values.Get("source"); v {
+	case "", sourceStored:
+	case sourceNASA:
+		query.Fresh = true
+	default:
+		return query, fmt.Errorf("invalid source %q, should be %v or %v", v, sourceStored, sourceNASA)
+	}
+
+	maxCount := maxRandomCount
+	if query.Fresh {
+		maxCount = models.MaxRandomFromNASA
+	}
+
+	if v := values.Get("count"); v != "" {
+		query.Count, err = strconv.Atoi(v)
+		if err != nil || query.Count < 1 || query.Count > maxCount {
+			return query, fmt.Errorf("invalid count %q, should be between 1 and %v", v, maxCount)
+		}
+	}
+
+	v := values.Get("seed")
+	switch {
+	case v != "" && query.Fresh:
+		return query, errors.New("seed can not be used with source nasa")
+	case v != "":
+		query.Seed, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid seed %q", v)
+		}
+	default:
+		query.Seed = rand.Int63() //nolint:gosec // seed does not need secure random
+	}
+
+	return query, nil
+}
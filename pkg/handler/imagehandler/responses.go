@@ -1,26 +1,80 @@
 package imagehandler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/handler/response"
+	"github.com/Dyleme/apod.git/pkg/models"
 )
 
-func responseError(w http.ResponseWriter, err error, statusCode int) {
-	bts, err := json.Marshal(err.Error())
-	if err != nil {
-		responseError(w, err, http.StatusInternalServerError)
+// responseServiceError responses with the status code corresponding to the error returned by the service.
+// Details of the errors are only logged, unknown errors are responded with 500.
+// Recorded transient failures are responded with Retry-After set to the time of the next download attempt.
+func responseServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var failureErr *models.FailureError
+	if errors.As(err, &failureErr) && !failureErr.Failure.Permanent {
+		setRetryAfter(w, time.Until(failureErr.Failure.RetryAfter))
 	}
 
-	w.WriteHeader(statusCode)
-	_, _ = w.Write(bts)
+	var (
+		rateLimitErr    *models.RateLimitError
+		notPublishedErr *models.NotPublishedError
+	)
+
+	switch {
+	case errors.As(err, &notPublishedErr):
+		setRetryAfter(w, time.Until(notPublishedErr.PublishAt))
+		response.Error(w, r, http.StatusNotFound, response.CodeNotPublished, err)
+	case errors.As(err, &rateLimitErr):
+		setRetryAfter(w, rateLimitErr.RetryAfter)
+		response.Error(w, r, http.StatusServiceUnavailable, response.CodeRateLimited, err)
+	case errors.Is(err, models.ErrRateLimited):
+		response.Error(w, r, http.StatusServiceUnavailable, response.CodeRateLimited, err)
+	case errors.Is(err, models.ErrAPODNotFound),
+		errors.Is(err, models.ErrImageNotExists),
+		errors.Is(err, models.ErrFileNotExists):
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, err)
+	case errors.Is(err, models.ErrDateOutOfRange):
+		response.Error(w, r, http.StatusUnprocessableEntity, response.CodeDateOutOfRange, err)
+	case errors.Is(err, models.ErrUnsupportedMediaType),
+		errors.Is(err, models.ErrDisallowedContentType),
+		errors.Is(err, models.ErrFileTooLarge):
+		response.Error(w, r, http.StatusUnprocessableEntity, response.CodeUnsupportedMedia, err)
+	case errors.Is(err, models.ErrUpstreamUnavailable),
+		errors.Is(err, context.DeadlineExceeded):
+		response.Error(w, r, http.StatusBadGateway, response.CodeUpstreamUnavailable, err)
+	case errors.Is(err, models.ErrRandomFromNASADisabled):
+		response.Error(w, r, http.StatusForbidden, response.CodeForbidden, err)
+	case errors.Is(err, models.ErrServiceClosed):
+		response.Error(w, r, http.StatusServiceUnavailable, response.CodeUnavailable, err)
+	default:
+		response.Internal(w, r, err)
+	}
 }
 
-func responseJSON(w http.ResponseWriter, v any) {
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
+
+func responseJSON(w http.ResponseWriter, r *http.Request, v any) {
 	bts, err := json.Marshal(v)
 	if err != nil {
-		responseError(w, err, http.StatusInternalServerError)
+		responseServiceError(w, r, err)
+
+		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write(bts)
 }
@@ -0,0 +1,82 @@
+// Package response writes the error envelope shared by all endpoints.
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// Codes of the error responses, clients can branch on them.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeUnauthorized        = "unauthorized"
+	CodeNotFound            = "not_found"
+	CodeNotPublished        = "not_published"
+	CodeForbidden           = "forbidden"
+	CodeDateOutOfRange      = "date_out_of_range"
+	CodeUnsupportedMedia    = "unsupported_media"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeRateLimited         = "rate_limited"
+	CodeUnavailable         = "unavailable"
+	CodeInternal            = "internal"
+)
+
+// messages are the messages of the error responses by their codes.
+// Details of the errors are only logged, they can contain urls of the upstream requests.
+var messages = map[string]string{
+	CodeBadRequest:          "invalid request parameters",
+	CodeUnauthorized:        "invalid admin token",
+	CodeNotFound:            "not found",
+	CodeNotPublished:        "apod of the date is not published yet",
+	CodeForbidden:           "forbidden",
+	CodeDateOutOfRange:      "date is out of the range of published apods",
+	CodeUnsupportedMedia:    "apod is not an image or its file is not allowed",
+	CodeUpstreamUnavailable: "nasa api is unavailable",
+	CodeRateLimited:         "nasa api rate limit is exceeded",
+	CodeUnavailable:         "service is unavailable",
+	CodeInternal:            "internal error",
+}
+
+// errorResponse is the body of every error response.
+// RequestID is the id of the request which can be found in the logs.
+type errorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Error responses with the error envelope which message is fixed for the code.
+// The error is logged with the request id, errors of the server side statuses are logged as errors.
+func Error(w http.ResponseWriter, r *http.Request, statusCode int, code string, err error) {
+	reqID := middleware.GetReqID(r.Context())
+
+	entry := logrus.WithFields(logrus.Fields{"request_id": reqID, "code": code})
+	if statusCode >= http.StatusInternalServerError {
+		entry.Error(err)
+	} else {
+		entry.Info(err)
+	}
+
+	bts, err := json.Marshal(errorResponse{
+		Code:      code,
+		Message:   messages[code],
+		RequestID: reqID,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(bts)
+}
+
+// Internal responses to the unexpected error with 500 without the details.
+func Internal(w http.ResponseWriter, r *http.Request, err error) {
+	Error(w, r, http.StatusInternalServerError, CodeInternal, err)
+}
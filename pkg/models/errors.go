@@ -1,5 +1,97 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 var ErrImageNotExists = fmt.Errorf("image not exists")
+
+// ErrAPODNotFound is returned when NASA has no APOD entry for the date.
+var ErrAPODNotFound = fmt.Errorf("apod not found")
+
+// ErrDateOutOfRange is returned for dates before the first APOD or after the last published one.
+var ErrDateOutOfRange = fmt.Errorf("date out of range")
+
+// ErrUpstreamUnavailable is returned when NASA can not be reached or fails to respond.
+var ErrUpstreamUnavailable = fmt.Errorf("upstream unavailable")
+
+var ErrFileNotExists = fmt.Errorf("file not exists")
+
+// ErrRandomFromNASADisabled is returned when random entries are requested from NASA, but it is not enabled.
+var ErrRandomFromNASADisabled = fmt.Errorf("random entries from nasa are disabled")
+
+var ErrUnsupportedMediaType = fmt.Errorf("unsupported media type")
+
+var ErrServiceClosed = fmt.Errorf("service closed")
+
+var ErrFileTooLarge = fmt.Errorf("file too large")
+
+var ErrRateLimited = fmt.Errorf("rate limited")
+
+// RateLimitError is returned when every NASA api key is throttled.
+// RetryAfter is the time after which one of the keys can be used again.
+// errors.Is(err, ErrRateLimited) reports true for it.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", ErrRateLimited, e.RetryAfter)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+var ErrDisallowedContentType = fmt.Errorf("disallowed content type")
+
+// ContentTypeError is returned when the downloaded file is not an allowed image.
+// errors.Is(err, ErrDisallowedContentType) reports true for it.
+type ContentTypeError struct {
+	Declared string
+	Detected string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("%v: detected %q, declared %q", ErrDisallowedContentType, e.Detected, e.Declared)
+}
+
+func (e *ContentTypeError) Is(target error) bool {
+	return target == ErrDisallowedContentType
+}
+
+var ErrFailureNotExists = fmt.Errorf("failure not exists")
+
+// FailureError is returned for the date which download failed recently, NASA is not called again.
+// It unwraps to the error of the failure reason, so it is handled like the original error.
+type FailureError struct {
+	Failure DownloadFailure
+}
+
+func (e *FailureError) Error() string {
+	return fmt.Sprintf("download failed at %v: %s", e.Failure.FailedAt.Format(time.RFC3339), e.Failure.Message)
+}
+
+func (e *FailureError) Unwrap() error {
+	return e.Failure.Reason.Err()
+}
+
+var ErrNotPublished = fmt.Errorf("not published yet")
+
+// NotPublishedError is returned for the date which APOD is not published yet.
+// PublishAt is the expected time of the publication.
+// errors.Is(err, ErrNotPublished) reports true for it.
+type NotPublishedError struct {
+	Date      time.Time
+	PublishAt time.Time
+}
+
+func (e *NotPublishedError) Error() string {
+	return fmt.Sprintf("apod of %v is %v, expected at %v",
+		e.Date.Format(time.DateOnly), ErrNotPublished, e.PublishAt.Format(time.RFC3339))
+}
+
+func (e *NotPublishedError) Is(target error) bool {
+	return target == ErrNotPublished
+}
@@ -1,8 +1,223 @@
 package models
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
+// Media types of the APOD entries.
+const (
+	MediaTypeImage = "image"
+	MediaTypeVideo = "video"
+)
+
+// FirstAPODDate is the date of the first APOD entry, there are no entries before it.
+var FirstAPODDate = time.Date(1995, time.June, 16, 0, 0, 0, 0, time.UTC)
+
+// APOD is the description of the astronomy picture of the day received from NASA.
+type APOD struct {
+	Date           time.Time
+	Title          string
+	Explanation    string
+	Copyright      string
+	MediaType      string
+	ServiceVersion string
+	HDURL          string
+	OriginalURL    string
+	ThumbnailURL   string
+}
+
+// IsVideo reports whether the APOD entry is a video.
+// For videos OriginalURL is the link to the video and the stored image is its thumbnail.
+func (a *APOD) IsVideo() bool {
+	return a.MediaType == MediaTypeVideo
+}
+
+// FileInfo describes the stored file.
+// Name and LastModified are filled only by the storage.
+type FileInfo struct {
+	Name         string
+	SHA256       string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// ObjectRef is the location of the stored file.
+type ObjectRef struct {
+	Bucket string
+	Key    string
+}
+
+// IsZero reports whether the reference points to no file.
+func (o ObjectRef) IsZero() bool {
+	return o.Key == ""
+}
+
+// GCReport is the result of the garbage collection of the storage.
+// Orphaned are names of the files which are not referenced by any record and older than the grace period.
+type GCReport struct {
+	Scanned  int
+	Orphaned []string
+	Deleted  int
+	DryRun   bool
+}
+
+// Rendition is the resized copy of the image generated after the image is stored.
+// URL is built when the rendition is read like the URL of the record.
+type Rendition struct {
+	Width  int
+	Height int
+	Object ObjectRef
+	File   FileInfo
+	URL    string
+}
+
+// AlbumRecord is the stored image with the description of its APOD.
+// Image is the location of the standard image and HDImage is the location of the HD image,
+// each of them is zero if the rendition is not stored.
+// URL and HDImageURL are links to the images, they are not stored and are built when the record is read.
+// Renditions are sorted by the width.
 type AlbumRecord struct {
-	URL  string
-	Date time.Time
+	Image       ObjectRef
+	HDImage     ObjectRef
+	URL         string
+	HDImageURL  string
+	ImageFile   FileInfo
+	HDImageFile FileInfo
+	Renditions  []Rendition
+	APOD
+}
+
+// ImageURL returns the path to the image of the requested quality.
+// If the rendition is not stored the path to the other one is returned.
+func (r *AlbumRecord) ImageURL(quality Quality) string {
+	if quality == QualityHD && r.HDImageURL != "" {
+		return r.HDImageURL
+	}
+
+	if r.URL == "" {
+		return r.HDImageURL
+	}
+
+	return r.URL
+}
+
+// AlbumCursor points to the position in the album, the page starts after or before the Date in the sort order.
+type AlbumCursor struct {
+	Date   time.Time
+	Before bool
+}
+
+// AlbumQuery selects the page of the album.
+// From and To limit the dates of the records inclusively, zero values do not limit them.
+// Records are sorted by the date, Limit is the maximal number of records in the page, zero selects the default.
+type AlbumQuery struct {
+	From       time.Time
+	To         time.Time
+	Descending bool
+	Limit      int
+	Cursor     *AlbumCursor
+}
+
+// AlbumPage is the page of the album.
+// Total is the number of records matching the filters of the query,
+// Next and Prev point to the adjacent pages, they are nil if there is no such page.
+type AlbumPage struct {
+	Records []AlbumRecord
+	Total   int
+	Next    *AlbumCursor
+	Prev    *AlbumCursor
+}
+
+// RandomQuery selects Count random records.
+// Stored records are picked by the random generator with the Seed, so the same seed gives the same picks
+// while the stored records do not change. If Fresh is set random entries are fetched from NASA and stored instead.
+type RandomQuery struct {
+	Count int
+	Seed  int64
+	Fresh bool
+}
+
+// MaxRandomFromNASA is the maximal Count of the RandomQuery with Fresh set.
+const MaxRandomFromNASA = 10
+
+// ImageObject returns the location and the info of the image of the requested quality.
+// If the rendition is not stored the other one is returned, like in ImageURL.
+func (r *AlbumRecord) ImageObject(quality Quality) (ObjectRef, FileInfo) {
+	if quality == QualityHD && !r.HDImage.IsZero() {
+		return r.HDImage, r.HDImageFile
+	}
+
+	if r.Image.IsZero() {
+		return r.HDImage, r.HDImageFile
+	}
+
+	return r.Image, r.ImageFile
+}
+
+// MediaFile is the opened stored image, the caller should close the Content.
+type MediaFile struct {
+	Content io.ReadSeekCloser
+	Info    FileInfo
+}
+
+// FailureReason is the kind of the failed download.
+type FailureReason string
+
+// Reasons of the failed downloads, they match the codes of the error responses.
+const (
+	FailureNotFound            FailureReason = "not_found"
+	FailureDateOutOfRange      FailureReason = "date_out_of_range"
+	FailureUnsupportedMedia    FailureReason = "unsupported_media"
+	FailureUpstreamUnavailable FailureReason = "upstream_unavailable"
+)
+
+// Err returns the error corresponding to the reason.
+func (r FailureReason) Err() error {
+	switch r {
+	case FailureNotFound:
+		return ErrAPODNotFound
+	case FailureDateOutOfRange:
+		return ErrDateOutOfRange
+	case FailureUnsupportedMedia:
+		return ErrUnsupportedMediaType
+	case FailureUpstreamUnavailable:
+		return ErrUpstreamUnavailable
+	default:
+		return nil
+	}
+}
+
+// DownloadFailure is the recorded failed download of the date.
+// Permanent failures are not retried until the record is cleared,
+// transient ones are retried after RetryAfter.
+type DownloadFailure struct {
+	Date       time.Time
+	Reason     FailureReason
+	Message    string
+	Permanent  bool
+	RetryAfter time.Time
+	FailedAt   time.Time
+}
+
+// Active reports whether the download of the date should not be retried at the moment now.
+func (f *DownloadFailure) Active(now time.Time) bool {
+	return f.Permanent || now.Before(f.RetryAfter)
+}
+
+// VerifyProblem is the inconsistency between the record and the stored file.
+type VerifyProblem struct {
+	Date    time.Time
+	Quality Quality
+	Name    string
+	Problem string
+}
+
+// VerifyReport is the result of the verification of the stored files.
+// Repaired are the dates which images were downloaded again.
+type VerifyReport struct {
+	Checked  int
+	Problems []VerifyProblem
+	Repaired []time.Time
 }
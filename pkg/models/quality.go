@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Quality is the rendition of the APOD image.
+type Quality string
+
+const (
+	// QualityStandard is the downscaled image from the APOD url field.
+	QualityStandard Quality = "standard"
+	// QualityHD is the full resolution image from the APOD hdurl field.
+	QualityHD Quality = "hd"
+)
+
+// ParseQuality converts provided string to the Quality.
+// Empty string is parsed as QualityStandard.
+func ParseQuality(s string) (Quality, error) {
+	switch q := Quality(s); q {
+	case "":
+		return QualityStandard, nil
+	case QualityStandard, QualityHD:
+		return q, nil
+	default:
+		return "", fmt.Errorf("unknown quality %q", s)
+	}
+}
+
+// QualityPolicy defines which renditions of the APOD image are downloaded.
+type QualityPolicy string
+
+const (
+	QualityPolicyStandard QualityPolicy = "standard"
+	QualityPolicyHD       QualityPolicy = "hd"
+	QualityPolicyBoth     QualityPolicy = "both"
+)
+
+// ParseQualityPolicy converts provided string to the QualityPolicy.
+// Empty string is parsed as QualityPolicyStandard.
+func ParseQualityPolicy(s string) (QualityPolicy, error) {
+	switch p := QualityPolicy(s); p {
+	case "":
+		return QualityPolicyStandard, nil
+	case QualityPolicyStandard, QualityPolicyHD, QualityPolicyBoth:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown quality policy %q", s)
+	}
+}
+
+// Qualities returns renditions which should be downloaded according to the policy.
+func (p QualityPolicy) Qualities() []Quality {
+	switch p {
+	case QualityPolicyHD:
+		return []Quality{QualityHD}
+	case QualityPolicyBoth:
+		return []Quality{QualityStandard, QualityHD}
+	case QualityPolicyStandard:
+		return []Quality{QualityStandard}
+	default:
+		return []Quality{QualityStandard}
+	}
+}
+
+// Image is the rendition of the APOD image which is being downloaded.
+// Size is the length of the Body, -1 if it is unknown.
+// ContentType is detected by the content of the image.
+type Image struct {
+	Quality     Quality
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+	Extension   string
+}
+
+// SaveImageFunc saves the image while it is being downloaded.
+type SaveImageFunc func(ctx context.Context, img *Image) error
@@ -0,0 +1,164 @@
+// Package rendition generates resized copies of the images.
+package rendition
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // decoding of the gif images
+	"image/jpeg"
+	_ "image/png" // decoding of the png images
+	"io"
+	"math"
+)
+
+const (
+	// ContentType is the content type of the generated renditions.
+	ContentType = "image/jpeg"
+	// Extension is the extension of the generated renditions.
+	Extension = ".jpg"
+
+	jpegQuality = 85
+	// maxPixels limits the size of the decoded image, 100 megapixels take 400MB of memory.
+	maxPixels = 100_000_000
+)
+
+// ErrImageTooLarge is returned when the image has too many pixels to decode it.
+var ErrImageTooLarge = errors.New("image is too large")
+
+// Rendition is the resized image encoded as jpeg.
+type Rendition struct {
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Supported reports whether the images of the content type can be decoded.
+// Empty content type of the images stored before it was recorded is supported.
+func Supported(contentType string) bool {
+	switch contentType {
+	case "", "image/jpeg", "image/png", "image/gif":
+		return true
+	default:
+		return false
+	}
+}
+
+// Generate decodes the image and returns its renditions of the provided widths keeping the aspect ratio.
+// Images are only downscaled, widths which are not less than the width of the image are skipped.
+// Jpeg, png and gif images are supported.
+func Generate(r io.Reader, widths []int) ([]Rendition, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image config: %w", err)
+	}
+
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %vx%v", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+
+	src := toRGBA(img)
+	renditions := make([]Rendition, 0, len(widths))
+
+	for _, w := range widths {
+		if w <= 0 || w >= src.Bounds().Dx() {
+			continue
+		}
+
+		dst := Resize(src, w)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("encode rendition %v: %w", w, err)
+		}
+
+		renditions = append(renditions, Rendition{
+			Width:  dst.Bounds().Dx(),
+			Height: dst.Bounds().Dy(),
+			Data:   buf.Bytes(),
+		})
+	}
+
+	return renditions, nil
+}
+
+// Resize downscales the image to the width keeping the aspect ratio.
+// Every pixel of the result is the average of the source pixels it covers.
+func Resize(src *image.RGBA, width int) *image.RGBA {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+
+	height := int(math.Round(float64(sh) * float64(width) / float64(sw)))
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := span(y, sh, height)
+
+		for x := 0; x < width; x++ {
+			x0, x1 := span(x, sw, width)
+
+			var r, g, b, a uint64
+
+			for sy := y0; sy < y1; sy++ {
+				off := src.PixOffset(sb.Min.X+x0, sb.Min.Y+sy)
+
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(src.Pix[off])
+					g += uint64(src.Pix[off+1])
+					b += uint64(src.Pix[off+2])
+					a += uint64(src.Pix[off+3])
+					off += 4
+				}
+			}
+
+			n := uint64((x1 - x0) * (y1 - y0))
+			off := dst.PixOffset(x, y)
+			dst.Pix[off] = uint8((r + n/2) / n)
+			dst.Pix[off+1] = uint8((g + n/2) / n)
+			dst.Pix[off+2] = uint8((b + n/2) / n)
+			dst.Pix[off+3] = uint8((a + n/2) / n)
+		}
+	}
+
+	return dst
+}
+
+// span returns the range of the source pixels covered by the destination pixel i.
+func span(i, srcSize, dstSize int) (int, int) {
+	start := i * srcSize / dstSize
+
+	end := (i + 1) * srcSize / dstSize
+	if end <= start {
+		end = start + 1
+	}
+
+	return start, end
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+
+	return rgba
+}
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apods
+    ADD COLUMN title text NOT NULL DEFAULT '',
+    ADD COLUMN explanation text NOT NULL DEFAULT '',
+    ADD COLUMN copyright text NOT NULL DEFAULT '',
+    ADD COLUMN media_type varchar(32) NOT NULL DEFAULT '',
+    ADD COLUMN service_version varchar(32) NOT NULL DEFAULT '',
+    ADD COLUMN hdurl text NOT NULL DEFAULT '',
+    ADD COLUMN original_url text NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE apods
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS explanation,
+    DROP COLUMN IF EXISTS copyright,
+    DROP COLUMN IF EXISTS media_type,
+    DROP COLUMN IF EXISTS service_version,
+    DROP COLUMN IF EXISTS hdurl,
+    DROP COLUMN IF EXISTS original_url;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apods
+    ADD COLUMN thumbnail_url text NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE apods
+    DROP COLUMN IF EXISTS thumbnail_url;
+-- +goose StatementEnd
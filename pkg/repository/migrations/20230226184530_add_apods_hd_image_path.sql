@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apods
+    ADD COLUMN hd_image_path varchar(251) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE apods
+    DROP COLUMN IF EXISTS hd_image_path;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apods
+    ADD COLUMN image_sha256 char(64) NOT NULL DEFAULT '',
+    ADD COLUMN image_size bigint NOT NULL DEFAULT 0,
+    ADD COLUMN image_content_type varchar(64) NOT NULL DEFAULT '',
+    ADD COLUMN hd_image_sha256 char(64) NOT NULL DEFAULT '',
+    ADD COLUMN hd_image_size bigint NOT NULL DEFAULT 0,
+    ADD COLUMN hd_image_content_type varchar(64) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE apods
+    DROP COLUMN IF EXISTS image_sha256,
+    DROP COLUMN IF EXISTS image_size,
+    DROP COLUMN IF EXISTS image_content_type,
+    DROP COLUMN IF EXISTS hd_image_sha256,
+    DROP COLUMN IF EXISTS hd_image_size,
+    DROP COLUMN IF EXISTS hd_image_content_type;
+-- +goose StatementEnd
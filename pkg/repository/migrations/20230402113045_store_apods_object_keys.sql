@@ -0,0 +1,69 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apods
+    ADD COLUMN image_bucket varchar(63) NOT NULL DEFAULT '',
+    ADD COLUMN hd_image_bucket varchar(63) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- content addressed keys have slashes: http://host/bucket/sha256/ab/cd/abcd...ext
+-- +goose StatementBegin
+UPDATE apods
+SET image_bucket = substring(image_path FROM '([^/]+)/sha256/[0-9a-f]{2}/[0-9a-f]{2}/[^/]+$'),
+    image_path = substring(image_path FROM '(sha256/[0-9a-f]{2}/[0-9a-f]{2}/[^/]+)$')
+WHERE image_path ~ '://.*/sha256/[0-9a-f]{2}/[0-9a-f]{2}/[^/]+$';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE apods
+SET hd_image_bucket = substring(hd_image_path FROM '([^/]+)/sha256/[0-9a-f]{2}/[0-9a-f]{2}/[^/]+$'),
+    hd_image_path = substring(hd_image_path FROM '(sha256/[0-9a-f]{2}/[0-9a-f]{2}/[^/]+)$')
+WHERE hd_image_path ~ '://.*/sha256/[0-9a-f]{2}/[0-9a-f]{2}/[^/]+$';
+-- +goose StatementEnd
+
+-- older keys have no slashes: http://host/bucket/name.ext
+-- +goose StatementBegin
+UPDATE apods
+SET image_bucket = substring(image_path FROM '([^/]+)/[^/]+$'),
+    image_path = substring(image_path FROM '([^/]+)$')
+WHERE image_path ~ '://';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE apods
+SET hd_image_bucket = substring(hd_image_path FROM '([^/]+)/[^/]+$'),
+    hd_image_path = substring(hd_image_path FROM '([^/]+)$')
+WHERE hd_image_path ~ '://';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apods RENAME COLUMN image_path TO image_key;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apods RENAME COLUMN hd_image_path TO hd_image_key;
+-- +goose StatementEnd
+
+-- +goose Down
+-- host of the links is not stored, so stored images can not be linked again.
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM apods WHERE image_key <> '' OR hd_image_key <> '') THEN
+        RAISE EXCEPTION 'object keys of stored apods can not be converted back to links, migrate down an empty table';
+    END IF;
+END $$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apods RENAME COLUMN image_key TO image_path;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apods RENAME COLUMN hd_image_key TO hd_image_path;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apods
+    DROP COLUMN IF EXISTS image_bucket,
+    DROP COLUMN IF EXISTS hd_image_bucket;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS renditions (
+    date date NOT NULL REFERENCES apods (date) ON DELETE CASCADE,
+    width integer NOT NULL,
+    height integer NOT NULL,
+    bucket varchar(63) NOT NULL,
+    key varchar(251) NOT NULL,
+    size bigint NOT NULL,
+    content_type varchar(64) NOT NULL,
+    PRIMARY KEY (date, width)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS renditions;
+-- +goose StatementEnd
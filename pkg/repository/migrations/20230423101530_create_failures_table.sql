@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS failures (
+    date date PRIMARY KEY,
+    reason varchar(32) NOT NULL,
+    message text NOT NULL,
+    permanent boolean NOT NULL,
+    retry_after timestamptz,
+    failed_at timestamptz NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS failures;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE renditions
+    ADD COLUMN sha256 char(64) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE renditions
+    DROP COLUMN IF EXISTS sha256;
+-- +goose StatementEnd
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Dyleme/apod.git/pkg/models"
@@ -27,44 +28,338 @@ func New(db *sql.DB) (*Repository, error) {
 	}, nil
 }
 
-func (r *Repository) AddImage(ctx context.Context, date time.Time, path string) error {
+// AddImage stores the record, the existing record of the same date is replaced.
+func (r *Repository) AddImage(ctx context.Context, record *models.AlbumRecord) error {
 	err := r.q.AddImage(ctx, r.db, queries.AddImageParams{
-		Date:      date,
-		ImagePath: path,
+		Date:               record.Date,
+		ImageBucket:        record.Image.Bucket,
+		ImageKey:           record.Image.Key,
+		HdImageBucket:      record.HDImage.Bucket,
+		HdImageKey:         record.HDImage.Key,
+		ImageSha256:        record.ImageFile.SHA256,
+		ImageSize:          record.ImageFile.Size,
+		ImageContentType:   record.ImageFile.ContentType,
+		HdImageSha256:      record.HDImageFile.SHA256,
+		HdImageSize:        record.HDImageFile.Size,
+		HdImageContentType: record.HDImageFile.ContentType,
+		Title:              record.Title,
+		Explanation:        record.Explanation,
+		Copyright:          record.Copyright,
+		MediaType:          record.MediaType,
+		ServiceVersion:     record.ServiceVersion,
+		Hdurl:              record.HDURL,
+		OriginalUrl:        record.OriginalURL,
+		ThumbnailUrl:       record.ThumbnailURL,
 	})
 	if err != nil {
-		return fmt.Errorf("set image path: %w", err)
+		return fmt.Errorf("add image: %w", err)
 	}
 
 	return nil
 }
 
-func (r *Repository) FetchImagePath(ctx context.Context, date time.Time) (string, error) {
-	path, err := r.q.FetchImagePath(ctx, r.db, date)
+func (r *Repository) FetchImage(ctx context.Context, date time.Time) (*models.AlbumRecord, error) {
+	image, err := r.q.FetchImage(ctx, r.db, date)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", models.ErrImageNotExists
+			return nil, models.ErrImageNotExists
 		}
 
-		return "", fmt.Errorf("fetch image path: %w", err)
+		return nil, fmt.Errorf("fetch image: %w", err)
 	}
 
-	return path, nil
+	record := toAlbumRecord(image)
+
+	renditions, err := r.q.FetchRenditions(ctx, r.db, date)
+	if err != nil {
+		return nil, fmt.Errorf("fetch renditions: %w", err)
+	}
+
+	for _, rend := range renditions {
+		record.Renditions = append(record.Renditions, toRendition(rend))
+	}
+
+	return &record, nil
 }
 
 func (r *Repository) FetchAlbum(ctx context.Context) ([]models.AlbumRecord, error) {
-	paths, err := r.q.FetchAlbum(ctx, r.db)
+	images, err := r.q.FetchAlbum(ctx, r.db)
 	if err != nil {
 		return nil, fmt.Errorf("fetch all images: %w", err)
 	}
 
-	album := make([]models.AlbumRecord, 0, len(paths))
-	for _, p := range paths {
-		album = append(album, models.AlbumRecord{
-			URL:  p.ImagePath,
-			Date: p.Date,
+	renditions, err := r.q.FetchAllRenditions(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("fetch renditions: %w", err)
+	}
+
+	return withRenditions(images, renditions), nil
+}
+
+// withRenditions converts images to the records with their renditions.
+func withRenditions(images []queries.Apod, renditions []queries.Rendition) []models.AlbumRecord {
+	byDate := make(map[time.Time][]models.Rendition)
+	for _, rend := range renditions {
+		byDate[rend.Date] = append(byDate[rend.Date], toRendition(rend))
+	}
+
+	album := make([]models.AlbumRecord, 0, len(images))
+	for _, img := range images {
+		record := toAlbumRecord(img)
+		record.Renditions = byDate[img.Date]
+		album = append(album, record)
+	}
+
+	return album
+}
+
+// FetchImagesByDates returns the records of the dates sorted by the date, dates without records are skipped.
+func (r *Repository) FetchImagesByDates(ctx context.Context, dates []time.Time) ([]models.AlbumRecord, error) {
+	if len(dates) == 0 {
+		return nil, nil
+	}
+
+	list := make([]string, 0, len(dates))
+	for _, d := range dates {
+		list = append(list, d.Format(time.DateOnly))
+	}
+
+	joined := strings.Join(list, ",")
+
+	images, err := r.q.FetchImagesByDates(ctx, r.db, joined)
+	if err != nil {
+		return nil, fmt.Errorf("fetch images by dates: %w", err)
+	}
+
+	renditions, err := r.q.FetchRenditionsByDates(ctx, r.db, joined)
+	if err != nil {
+		return nil, fmt.Errorf("fetch renditions: %w", err)
+	}
+
+	return withRenditions(images, renditions), nil
+}
+
+// Bounds of the date range when the query does not limit it.
+var (
+	minDate = time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	maxDate = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+)
+
+func dateRange(from, to time.Time) (time.Time, time.Time) {
+	if from.IsZero() {
+		from = minDate
+	}
+
+	if to.IsZero() {
+		to = maxDate
+	}
+
+	return from, to
+}
+
+// FetchAlbumPage returns at most limit records with dates between from and to inclusive sorted by the date.
+// Zero from and to do not limit the dates.
+func (r *Repository) FetchAlbumPage(ctx context.Context, from, to time.Time, desc bool, limit int) ([]models.AlbumRecord, error) {
+	from, to = dateRange(from, to)
+
+	var (
+		images []queries.Apod
+		err    error
+	)
+
+	if desc {
+		images, err = r.q.FetchAlbumPageDesc(ctx, r.db, queries.FetchAlbumPageDescParams{Date: from, Date_2: to, Limit: int32(limit)})
+	} else {
+		images, err = r.q.FetchAlbumPageAsc(ctx, r.db, queries.FetchAlbumPageAscParams{Date: from, Date_2: to, Limit: int32(limit)})
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("fetch album page: %w", err)
+	}
+
+	if len(images) == 0 {
+		return nil, nil
+	}
+
+	first, last := images[0].Date, images[len(images)-1].Date
+	if desc {
+		first, last = last, first
+	}
+
+	renditions, err := r.q.FetchRenditionsInRange(ctx, r.db, queries.FetchRenditionsInRangeParams{Date: first, Date_2: last})
+	if err != nil {
+		return nil, fmt.Errorf("fetch renditions: %w", err)
+	}
+
+	return withRenditions(images, renditions), nil
+}
+
+// CountAlbum returns the number of records with dates between from and to inclusive.
+// Zero from and to do not limit the dates.
+func (r *Repository) CountAlbum(ctx context.Context, from, to time.Time) (int, error) {
+	from, to = dateRange(from, to)
+
+	count, err := r.q.CountAlbum(ctx, r.db, queries.CountAlbumParams{Date: from, Date_2: to})
+	if err != nil {
+		return 0, fmt.Errorf("count album: %w", err)
+	}
+
+	return int(count), nil
+}
+
+// SetRenditions replaces renditions of the date with provided ones.
+func (r *Repository) SetRenditions(ctx context.Context, date time.Time, renditions []models.Rendition) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit returns error
+
+	if err := r.q.DeleteRenditions(ctx, tx, date); err != nil {
+		return fmt.Errorf("delete renditions: %w", err)
+	}
+
+	for _, rend := range renditions {
+		err := r.q.AddRendition(ctx, tx, queries.AddRenditionParams{
+			Date:        date,
+			Width:       int32(rend.Width),
+			Height:      int32(rend.Height),
+			Bucket:      rend.Object.Bucket,
+			Key:         rend.Object.Key,
+			Size:        rend.File.Size,
+			ContentType: rend.File.ContentType,
+			Sha256:      rend.File.SHA256,
 		})
+		if err != nil {
+			return fmt.Errorf("add rendition %v: %w", rend.Width, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) FetchDatesInRange(ctx context.Context, from, to time.Time) ([]time.Time, error) {
+	dates, err := r.q.FetchDatesInRange(ctx, r.db, queries.FetchDatesInRangeParams{
+		Date:   from,
+		Date_2: to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetch dates in range: %w", err)
+	}
+
+	return dates, nil
+}
+
+// FetchImageKeys returns locations of all stored images of every quality.
+func (r *Repository) FetchImageKeys(ctx context.Context) ([]models.ObjectRef, error) {
+	rows, err := r.q.FetchImageKeys(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("fetch image keys: %w", err)
+	}
+
+	refs := make([]models.ObjectRef, 0, len(rows))
+	for _, row := range rows {
+		refs = append(refs, models.ObjectRef{Bucket: row.Bucket, Key: row.Key})
+	}
+
+	return refs, nil
+}
+
+// AddFailure records the failed download, the existing failure of the same date is replaced.
+func (r *Repository) AddFailure(ctx context.Context, failure *models.DownloadFailure) error {
+	err := r.q.AddFailure(ctx, r.db, queries.AddFailureParams{
+		Date:       failure.Date,
+		Reason:     string(failure.Reason),
+		Message:    failure.Message,
+		Permanent:  failure.Permanent,
+		RetryAfter: sql.NullTime{Time: failure.RetryAfter, Valid: !failure.RetryAfter.IsZero()},
+		FailedAt:   failure.FailedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("add failure: %w", err)
+	}
+
+	return nil
+}
+
+// FetchFailure returns the recorded failed download of the date.
+// If there is no such record models.ErrFailureNotExists is returned.
+func (r *Repository) FetchFailure(ctx context.Context, date time.Time) (*models.DownloadFailure, error) {
+	f, err := r.q.FetchFailure(ctx, r.db, date)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrFailureNotExists
+		}
+
+		return nil, fmt.Errorf("fetch failure: %w", err)
+	}
+
+	return &models.DownloadFailure{
+		Date:       f.Date,
+		Reason:     models.FailureReason(f.Reason),
+		Message:    f.Message,
+		Permanent:  f.Permanent,
+		RetryAfter: f.RetryAfter.Time,
+		FailedAt:   f.FailedAt,
+	}, nil
+}
+
+// DeleteFailure removes the recorded failed download of the date.
+// If there is no such record models.ErrFailureNotExists is returned.
+func (r *Repository) DeleteFailure(ctx context.Context, date time.Time) error {
+	deleted, err := r.q.DeleteFailure(ctx, r.db, date)
+	if err != nil {
+		return fmt.Errorf("delete failure: %w", err)
+	}
+
+	if deleted == 0 {
+		return models.ErrFailureNotExists
 	}
 
-	return album, nil
+	return nil
+}
+
+func toAlbumRecord(a queries.Apod) models.AlbumRecord {
+	return models.AlbumRecord{
+		Image:   models.ObjectRef{Bucket: a.ImageBucket, Key: a.ImageKey},
+		HDImage: models.ObjectRef{Bucket: a.HdImageBucket, Key: a.HdImageKey},
+		ImageFile: models.FileInfo{
+			SHA256:      a.ImageSha256,
+			Size:        a.ImageSize,
+			ContentType: a.ImageContentType,
+		},
+		HDImageFile: models.FileInfo{
+			SHA256:      a.HdImageSha256,
+			Size:        a.HdImageSize,
+			ContentType: a.HdImageContentType,
+		},
+		APOD: models.APOD{
+			Date:           a.Date,
+			Title:          a.Title,
+			Explanation:    a.Explanation,
+			Copyright:      a.Copyright,
+			MediaType:      a.MediaType,
+			ServiceVersion: a.ServiceVersion,
+			HDURL:          a.Hdurl,
+			OriginalURL:    a.OriginalUrl,
+			ThumbnailURL:   a.ThumbnailUrl,
+		},
+	}
+}
+
+func toRendition(r queries.Rendition) models.Rendition {
+	return models.Rendition{
+		Width:  int(r.Width),
+		Height: int(r.Height),
+		Object: models.ObjectRef{Bucket: r.Bucket, Key: r.Key},
+		File: models.FileInfo{
+			SHA256:      r.Sha256,
+			Size:        r.Size,
+			ContentType: r.ContentType,
+		},
+	}
 }
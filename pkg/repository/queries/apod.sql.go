@@ -12,28 +12,161 @@ import (
 
 const addImage = `-- name: AddImage :exec
 INSERT INTO apods 
-(date, image_path)
-VALUES ($1, $2)
+(date, image_key, title, explanation, copyright, media_type, service_version, hdurl, original_url, thumbnail_url, hd_image_key,
+ image_sha256, image_size, image_content_type, hd_image_sha256, hd_image_size, hd_image_content_type, image_bucket, hd_image_bucket)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+ON CONFLICT (date) DO UPDATE SET
+    image_key = EXCLUDED.image_key,
+    title = EXCLUDED.title,
+    explanation = EXCLUDED.explanation,
+    copyright = EXCLUDED.copyright,
+    media_type = EXCLUDED.media_type,
+    service_version = EXCLUDED.service_version,
+    hdurl = EXCLUDED.hdurl,
+    original_url = EXCLUDED.original_url,
+    thumbnail_url = EXCLUDED.thumbnail_url,
+    hd_image_key = EXCLUDED.hd_image_key,
+    image_sha256 = EXCLUDED.image_sha256,
+    image_size = EXCLUDED.image_size,
+    image_content_type = EXCLUDED.image_content_type,
+    hd_image_sha256 = EXCLUDED.hd_image_sha256,
+    hd_image_size = EXCLUDED.hd_image_size,
+    hd_image_content_type = EXCLUDED.hd_image_content_type,
+    image_bucket = EXCLUDED.image_bucket,
+    hd_image_bucket = EXCLUDED.hd_image_bucket
 `
 
 type AddImageParams struct {
-	Date      time.Time
-	ImagePath string
+	Date               time.Time
+	ImageKey           string
+	Title              string
+	Explanation        string
+	Copyright          string
+	MediaType          string
+	ServiceVersion     string
+	Hdurl              string
+	OriginalUrl        string
+	ThumbnailUrl       string
+	HdImageKey         string
+	ImageSha256        string
+	ImageSize          int64
+	ImageContentType   string
+	HdImageSha256      string
+	HdImageSize        int64
+	HdImageContentType string
+	ImageBucket        string
+	HdImageBucket      string
 }
 
 func (q *Queries) AddImage(ctx context.Context, db DBTX, arg AddImageParams) error {
-	_, err := db.ExecContext(ctx, addImage, arg.Date, arg.ImagePath)
+	_, err := db.ExecContext(ctx, addImage,
+		arg.Date,
+		arg.ImageKey,
+		arg.Title,
+		arg.Explanation,
+		arg.Copyright,
+		arg.MediaType,
+		arg.ServiceVersion,
+		arg.Hdurl,
+		arg.OriginalUrl,
+		arg.ThumbnailUrl,
+		arg.HdImageKey,
+		arg.ImageSha256,
+		arg.ImageSize,
+		arg.ImageContentType,
+		arg.HdImageSha256,
+		arg.HdImageSize,
+		arg.HdImageContentType,
+		arg.ImageBucket,
+		arg.HdImageBucket,
+	)
 	return err
 }
 
-const fetchAllImagePaths = `-- name: FetchAllImagePaths :many
-SELECT date, image_path
+const countAlbum = `-- name: CountAlbum :one
+SELECT count(*)
 FROM apods
-WHERE image_path IS NOT NULL
+WHERE date BETWEEN $1 AND $2
+`
+
+type CountAlbumParams struct {
+	Date   time.Time
+	Date_2 time.Time
+}
+
+func (q *Queries) CountAlbum(ctx context.Context, db DBTX, arg CountAlbumParams) (int64, error) {
+	row := db.QueryRowContext(ctx, countAlbum, arg.Date, arg.Date_2)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const fetchAlbum = `-- name: FetchAlbum :many
+SELECT date, image_key, title, explanation, copyright, media_type, service_version, hdurl, original_url, thumbnail_url, hd_image_key, image_sha256, image_size, image_content_type, hd_image_sha256, hd_image_size, hd_image_content_type, image_bucket, hd_image_bucket
+FROM apods
+ORDER BY date
 `
 
 func (q *Queries) FetchAlbum(ctx context.Context, db DBTX) ([]Apod, error) {
-	rows, err := db.QueryContext(ctx, fetchAllImagePaths)
+	rows, err := db.QueryContext(ctx, fetchAlbum)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Apod
+	for rows.Next() {
+		var i Apod
+		if err := rows.Scan(
+			&i.Date,
+			&i.ImageKey,
+			&i.Title,
+			&i.Explanation,
+			&i.Copyright,
+			&i.MediaType,
+			&i.ServiceVersion,
+			&i.Hdurl,
+			&i.OriginalUrl,
+			&i.ThumbnailUrl,
+			&i.HdImageKey,
+			&i.ImageSha256,
+			&i.ImageSize,
+			&i.ImageContentType,
+			&i.HdImageSha256,
+			&i.HdImageSize,
+			&i.HdImageContentType,
+			&i.ImageBucket,
+			&i.HdImageBucket,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchAlbumPageAsc = `-- name: FetchAlbumPageAsc :many
+SELECT date, image_key, title, explanation, copyright, media_type, service_version, hdurl, original_url, thumbnail_url, hd_image_key, image_sha256, image_size, image_content_type, hd_image_sha256, hd_image_size, hd_image_content_type, image_bucket, hd_image_bucket
+FROM apods
+WHERE date BETWEEN $1 AND $2
+ORDER BY date ASC
+LIMIT $3
+`
+
+type FetchAlbumPageAscParams struct {
+	Date   time.Time
+	Date_2 time.Time
+	Limit  int32
+}
+
+// Pages of the album are selected by the date range, the primary key index serves both sort orders.
+func (q *Queries) FetchAlbumPageAsc(ctx context.Context, db DBTX, arg FetchAlbumPageAscParams) ([]Apod, error) {
+	rows, err := db.QueryContext(ctx, fetchAlbumPageAsc, arg.Date, arg.Date_2, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +174,27 @@ func (q *Queries) FetchAlbum(ctx context.Context, db DBTX) ([]Apod, error) {
 	var items []Apod
 	for rows.Next() {
 		var i Apod
-		if err := rows.Scan(&i.Date, &i.ImagePath); err != nil {
+		if err := rows.Scan(
+			&i.Date,
+			&i.ImageKey,
+			&i.Title,
+			&i.Explanation,
+			&i.Copyright,
+			&i.MediaType,
+			&i.ServiceVersion,
+			&i.Hdurl,
+			&i.OriginalUrl,
+			&i.ThumbnailUrl,
+			&i.HdImageKey,
+			&i.ImageSha256,
+			&i.ImageSize,
+			&i.ImageContentType,
+			&i.HdImageSha256,
+			&i.HdImageSize,
+			&i.HdImageContentType,
+			&i.ImageBucket,
+			&i.HdImageBucket,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -55,15 +208,217 @@ func (q *Queries) FetchAlbum(ctx context.Context, db DBTX) ([]Apod, error) {
 	return items, nil
 }
 
-const fetchImagePath = `-- name: FetchImagePath :one
-SELECT image_path
+const fetchAlbumPageDesc = `-- name: FetchAlbumPageDesc :many
+SELECT date, image_key, title, explanation, copyright, media_type, service_version, hdurl, original_url, thumbnail_url, hd_image_key, image_sha256, image_size, image_content_type, hd_image_sha256, hd_image_size, hd_image_content_type, image_bucket, hd_image_bucket
+FROM apods
+WHERE date BETWEEN $1 AND $2
+ORDER BY date DESC
+LIMIT $3
+`
+
+type FetchAlbumPageDescParams struct {
+	Date   time.Time
+	Date_2 time.Time
+	Limit  int32
+}
+
+func (q *Queries) FetchAlbumPageDesc(ctx context.Context, db DBTX, arg FetchAlbumPageDescParams) ([]Apod, error) {
+	rows, err := db.QueryContext(ctx, fetchAlbumPageDesc, arg.Date, arg.Date_2, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Apod
+	for rows.Next() {
+		var i Apod
+		if err := rows.Scan(
+			&i.Date,
+			&i.ImageKey,
+			&i.Title,
+			&i.Explanation,
+			&i.Copyright,
+			&i.MediaType,
+			&i.ServiceVersion,
+			&i.Hdurl,
+			&i.OriginalUrl,
+			&i.ThumbnailUrl,
+			&i.HdImageKey,
+			&i.ImageSha256,
+			&i.ImageSize,
+			&i.ImageContentType,
+			&i.HdImageSha256,
+			&i.HdImageSize,
+			&i.HdImageContentType,
+			&i.ImageBucket,
+			&i.HdImageBucket,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchDatesInRange = `-- name: FetchDatesInRange :many
+SELECT date
+FROM apods
+WHERE date BETWEEN $1 AND $2
+`
+
+type FetchDatesInRangeParams struct {
+	Date   time.Time
+	Date_2 time.Time
+}
+
+func (q *Queries) FetchDatesInRange(ctx context.Context, db DBTX, arg FetchDatesInRangeParams) ([]time.Time, error) {
+	rows, err := db.QueryContext(ctx, fetchDatesInRange, arg.Date, arg.Date_2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var date time.Time
+		if err := rows.Scan(&date); err != nil {
+			return nil, err
+		}
+		items = append(items, date)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchImage = `-- name: FetchImage :one
+SELECT date, image_key, title, explanation, copyright, media_type, service_version, hdurl, original_url, thumbnail_url, hd_image_key, image_sha256, image_size, image_content_type, hd_image_sha256, hd_image_size, hd_image_content_type, image_bucket, hd_image_bucket
 FROM apods
 WHERE date = $1
 `
 
-func (q *Queries) FetchImagePath(ctx context.Context, db DBTX, date time.Time) (string, error) {
-	row := db.QueryRowContext(ctx, fetchImagePath, date)
-	var image_path string
-	err := row.Scan(&image_path)
-	return image_path, err
+func (q *Queries) FetchImage(ctx context.Context, db DBTX, date time.Time) (Apod, error) {
+	row := db.QueryRowContext(ctx, fetchImage, date)
+	var i Apod
+	err := row.Scan(
+		&i.Date,
+		&i.ImageKey,
+		&i.Title,
+		&i.Explanation,
+		&i.Copyright,
+		&i.MediaType,
+		&i.ServiceVersion,
+		&i.Hdurl,
+		&i.OriginalUrl,
+		&i.ThumbnailUrl,
+		&i.HdImageKey,
+		&i.ImageSha256,
+		&i.ImageSize,
+		&i.ImageContentType,
+		&i.HdImageSha256,
+		&i.HdImageSize,
+		&i.HdImageContentType,
+		&i.ImageBucket,
+		&i.HdImageBucket,
+	)
+	return i, err
+}
+
+const fetchImageKeys = `-- name: FetchImageKeys :many
+SELECT image_bucket AS bucket, image_key AS key
+FROM apods
+WHERE image_key <> ''
+UNION
+SELECT hd_image_bucket AS bucket, hd_image_key AS key
+FROM apods
+WHERE hd_image_key <> ''
+UNION
+SELECT bucket, key
+FROM renditions
+`
+
+type FetchImageKeysRow struct {
+	Bucket string
+	Key    string
+}
+
+func (q *Queries) FetchImageKeys(ctx context.Context, db DBTX) ([]FetchImageKeysRow, error) {
+	rows, err := db.QueryContext(ctx, fetchImageKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FetchImageKeysRow
+	for rows.Next() {
+		var i FetchImageKeysRow
+		if err := rows.Scan(&i.Bucket, &i.Key); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchImagesByDates = `-- name: FetchImagesByDates :many
+SELECT date, image_key, title, explanation, copyright, media_type, service_version, hdurl, original_url, thumbnail_url, hd_image_key, image_sha256, image_size, image_content_type, hd_image_sha256, hd_image_size, hd_image_content_type, image_bucket, hd_image_bucket
+FROM apods
+WHERE date = ANY(string_to_array($1::text, ',')::date[])
+ORDER BY date
+`
+
+func (q *Queries) FetchImagesByDates(ctx context.Context, db DBTX, dates string) ([]Apod, error) {
+	rows, err := db.QueryContext(ctx, fetchImagesByDates, dates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Apod
+	for rows.Next() {
+		var i Apod
+		if err := rows.Scan(
+			&i.Date,
+			&i.ImageKey,
+			&i.Title,
+			&i.Explanation,
+			&i.Copyright,
+			&i.MediaType,
+			&i.ServiceVersion,
+			&i.Hdurl,
+			&i.OriginalUrl,
+			&i.ThumbnailUrl,
+			&i.HdImageKey,
+			&i.ImageSha256,
+			&i.ImageSize,
+			&i.ImageContentType,
+			&i.HdImageSha256,
+			&i.HdImageSize,
+			&i.HdImageContentType,
+			&i.ImageBucket,
+			&i.HdImageBucket,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
@@ -5,10 +5,48 @@
 package queries
 
 import (
+	"database/sql"
 	"time"
 )
 
 type Apod struct {
-	Date      time.Time
-	ImagePath string
+	Date               time.Time
+	ImageKey           string
+	Title              string
+	Explanation        string
+	Copyright          string
+	MediaType          string
+	ServiceVersion     string
+	Hdurl              string
+	OriginalUrl        string
+	ThumbnailUrl       string
+	HdImageKey         string
+	ImageSha256        string
+	ImageSize          int64
+	ImageContentType   string
+	HdImageSha256      string
+	HdImageSize        int64
+	HdImageContentType string
+	ImageBucket        string
+	HdImageBucket      string
+}
+
+type Failure struct {
+	Date       time.Time
+	Reason     string
+	Message    string
+	Permanent  bool
+	RetryAfter sql.NullTime
+	FailedAt   time.Time
+}
+
+type Rendition struct {
+	Date        time.Time
+	Width       int32
+	Height      int32
+	Bucket      string
+	Key         string
+	Size        int64
+	ContentType string
+	Sha256      string
 }
@@ -1,14 +1,78 @@
 -- name: AddImage :exec
 INSERT INTO apods 
-(date, image_path)
-VALUES ($1, $2);
+(date, image_key, title, explanation, copyright, media_type, service_version, hdurl, original_url, thumbnail_url, hd_image_key,
+ image_sha256, image_size, image_content_type, hd_image_sha256, hd_image_size, hd_image_content_type, image_bucket, hd_image_bucket)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+ON CONFLICT (date) DO UPDATE SET
+    image_key = EXCLUDED.image_key,
+    title = EXCLUDED.title,
+    explanation = EXCLUDED.explanation,
+    copyright = EXCLUDED.copyright,
+    media_type = EXCLUDED.media_type,
+    service_version = EXCLUDED.service_version,
+    hdurl = EXCLUDED.hdurl,
+    original_url = EXCLUDED.original_url,
+    thumbnail_url = EXCLUDED.thumbnail_url,
+    hd_image_key = EXCLUDED.hd_image_key,
+    image_sha256 = EXCLUDED.image_sha256,
+    image_size = EXCLUDED.image_size,
+    image_content_type = EXCLUDED.image_content_type,
+    hd_image_sha256 = EXCLUDED.hd_image_sha256,
+    hd_image_size = EXCLUDED.hd_image_size,
+    hd_image_content_type = EXCLUDED.hd_image_content_type,
+    image_bucket = EXCLUDED.image_bucket,
+    hd_image_bucket = EXCLUDED.hd_image_bucket;
 
--- name: FetchImagePath :one
-SELECT image_path
+-- name: FetchImage :one
+SELECT *
 FROM apods
 WHERE date = $1;
 
--- name: FetchAllImagePaths :many
-SELECT date, image_path
+-- name: FetchAlbum :many
+SELECT *
 FROM apods
-WHERE image_path IS NOT NULL ;
\ No newline at end of file
+ORDER BY date;
+
+
+-- name: FetchDatesInRange :many
+SELECT date
+FROM apods
+WHERE date BETWEEN $1 AND $2;
+
+-- name: FetchImageKeys :many
+SELECT image_bucket AS bucket, image_key AS key
+FROM apods
+WHERE image_key <> ''
+UNION
+SELECT hd_image_bucket AS bucket, hd_image_key AS key
+FROM apods
+WHERE hd_image_key <> ''
+UNION
+SELECT bucket, key
+FROM renditions;
+
+-- name: FetchAlbumPageAsc :many
+-- Pages of the album are selected by the date range, the primary key index serves both sort orders.
+SELECT *
+FROM apods
+WHERE date BETWEEN $1 AND $2
+ORDER BY date ASC
+LIMIT $3;
+
+-- name: FetchAlbumPageDesc :many
+SELECT *
+FROM apods
+WHERE date BETWEEN $1 AND $2
+ORDER BY date DESC
+LIMIT $3;
+
+-- name: CountAlbum :one
+SELECT count(*)
+FROM apods
+WHERE date BETWEEN $1 AND $2;
+
+-- name: FetchImagesByDates :many
+SELECT *
+FROM apods
+WHERE date = ANY(string_to_array(sqlc.arg(dates)::text, ',')::date[])
+ORDER BY date;
@@ -0,0 +1,19 @@
+-- name: AddFailure :exec
+INSERT INTO failures
+(date, reason, message, permanent, retry_after, failed_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (date) DO UPDATE SET
+    reason = EXCLUDED.reason,
+    message = EXCLUDED.message,
+    permanent = EXCLUDED.permanent,
+    retry_after = EXCLUDED.retry_after,
+    failed_at = EXCLUDED.failed_at;
+
+-- name: DeleteFailure :execrows
+DELETE FROM failures
+WHERE date = $1;
+
+-- name: FetchFailure :one
+SELECT *
+FROM failures
+WHERE date = $1;
@@ -0,0 +1,31 @@
+-- name: AddRendition :exec
+INSERT INTO renditions
+(date, width, height, bucket, key, size, content_type, sha256)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: DeleteRenditions :exec
+DELETE FROM renditions
+WHERE date = $1;
+
+-- name: FetchRenditions :many
+SELECT *
+FROM renditions
+WHERE date = $1
+ORDER BY width;
+
+-- name: FetchRenditionsByDates :many
+SELECT *
+FROM renditions
+WHERE date = ANY(string_to_array(sqlc.arg(dates)::text, ',')::date[])
+ORDER BY date, width;
+
+-- name: FetchAllRenditions :many
+SELECT *
+FROM renditions
+ORDER BY date, width;
+
+-- name: FetchRenditionsInRange :many
+SELECT *
+FROM renditions
+WHERE date BETWEEN $1 AND $2
+ORDER BY date, width;
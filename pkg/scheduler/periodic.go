@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Periodic runs the job with the fixed interval.
+type Periodic struct {
+	name     string
+	interval time.Duration
+	job      func(ctx context.Context) error
+}
+
+func NewPeriodic(name string, interval time.Duration, job func(ctx context.Context) error) *Periodic {
+	return &Periodic{name: name, interval: interval, job: job}
+}
+
+// Run method blocks and runs the job every interval until the context is done.
+// Errors of the job are logged, the job is run again after the next interval.
+func (p *Periodic) Run(ctx context.Context) {
+	logrus.Infof("start %s every %v", p.name, p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.job(ctx); err != nil {
+				logrus.Errorf("%s: %v", p.name, err)
+			}
+		case <-ctx.Done():
+			logrus.Infof("%s stopped", p.name)
+
+			return
+		}
+	}
+}
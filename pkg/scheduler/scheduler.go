@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is a config of the daily synchronization.
+// RetryInterval is the interval between attempts to fetch the APOD which is not published yet.
+type Config struct {
+	RetryInterval time.Duration
+}
+
+// Syncer fetches APODs and knows when they are published.
+type Syncer interface {
+	GetImageForDate(ctx context.Context, date time.Time) (*models.AlbumRecord, error)
+	CurrentDate(now time.Time) time.Time
+	PublishTime(date time.Time) time.Time
+}
+
+// DailySync fetches the APOD of the day once it is published.
+type DailySync struct {
+	cfg        Config
+	syncer     Syncer
+	lastSynced time.Time
+}
+
+func New(cfg Config, syncer Syncer) *DailySync {
+	return &DailySync{cfg: cfg, syncer: syncer}
+}
+
+// Run method blocks and fetches the APOD of every day at its publication time until the context is done.
+// If the APOD of the current day is not fetched yet, it is fetched immediately.
+func (ds *DailySync) Run(ctx context.Context) {
+	logrus.Info("start daily sync")
+
+	for {
+		date := ds.syncer.CurrentDate(time.Now())
+
+		if !date.Equal(ds.lastSynced) {
+			ds.sync(ctx, date)
+
+			if ctx.Err() != nil {
+				break
+			}
+
+			ds.lastSynced = date
+
+			continue
+		}
+
+		timer := time.NewTimer(time.Until(ds.syncer.PublishTime(date.AddDate(0, 0, 1))))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	logrus.Info("daily sync stopped")
+}
+
+// sync fetches the APOD of the date retrying until it succeeds or the context is done.
+// Retrying stops on the permanent errors and when the day of APOD rolls over.
+func (ds *DailySync) sync(ctx context.Context, date time.Time) {
+	for {
+		_, err := ds.syncer.GetImageForDate(ctx, date)
+		if err == nil {
+			logrus.Infof("daily sync: apod for %v is stored", date.Format(time.DateOnly))
+
+			return
+		}
+
+		logrus.Errorf("daily sync: apod for %v: %v", date.Format(time.DateOnly), err)
+
+		if isPermanent(err) {
+			return
+		}
+
+		timer := time.NewTimer(ds.cfg.RetryInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+		}
+
+		if !ds.syncer.CurrentDate(time.Now()).Equal(date) {
+			logrus.Errorf("daily sync: apod for %v is not stored, the day is over", date.Format(time.DateOnly))
+
+			return
+		}
+	}
+}
+
+// isPermanent reports whether the error can not be fixed by retrying the download of the date.
+func isPermanent(err error) bool {
+	var failureErr *models.FailureError
+	if errors.As(err, &failureErr) {
+		return failureErr.Failure.Permanent
+	}
+
+	return errors.Is(err, models.ErrAPODNotFound) ||
+		errors.Is(err, models.ErrUnsupportedMediaType) ||
+		errors.Is(err, models.ErrDisallowedContentType) ||
+		errors.Is(err, models.ErrFileTooLarge)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+// albumRepo serves the album of the records of the dates, other methods of the Repository are not implemented.
+type albumRepo struct {
+	Repository
+	dates []time.Time
+}
+
+func (r *albumRepo) inRange(from, to time.Time) []time.Time {
+	var dates []time.Time
+
+	for _, d := range r.dates {
+		if (from.IsZero() || !d.Before(from)) && (to.IsZero() || !d.After(to)) {
+			dates = append(dates, d)
+		}
+	}
+
+	return dates
+}
+
+func (r *albumRepo) CountAlbum(_ context.Context, from, to time.Time) (int, error) {
+	return len(r.inRange(from, to)), nil
+}
+
+func (r *albumRepo) FetchAlbumPage(_ context.Context, from, to time.Time, desc bool, limit int) ([]models.AlbumRecord, error) {
+	dates := r.inRange(from, to)
+
+	records := make([]models.AlbumRecord, 0, len(dates))
+	for i := range dates {
+		d := dates[i]
+		if desc {
+			d = dates[len(dates)-1-i]
+		}
+
+		records = append(records, models.AlbumRecord{APOD: models.APOD{Date: d}})
+	}
+
+	if len(records) > limit {
+		records = records[:limit]
+	}
+
+	return records, nil
+}
+
+func day(d int) time.Time {
+	return time.Date(2023, time.January, d, 0, 0, 0, 0, time.UTC)
+}
+
+func after(d int) *models.AlbumCursor {
+	return &models.AlbumCursor{Date: day(d)}
+}
+
+func before(d int) *models.AlbumCursor {
+	return &models.AlbumCursor{Date: day(d), Before: true}
+}
+
+func TestGetAlbum(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query models.AlbumQuery
+		want  []int
+		total int
+		next  *models.AlbumCursor
+		prev  *models.AlbumCursor
+	}{
+		{
+			name:  "ascending first page",
+			query: models.AlbumQuery{Limit: 2},
+			want:  []int{1, 2},
+			total: 5,
+			next:  after(2),
+		},
+		{
+			name:  "ascending middle page",
+			query: models.AlbumQuery{Limit: 2, Cursor: after(2)},
+			want:  []int{3, 4},
+			total: 5,
+			next:  after(4),
+			prev:  before(3),
+		},
+		{
+			name:  "ascending last page",
+			query: models.AlbumQuery{Limit: 2, Cursor: after(4)},
+			want:  []int{5},
+			total: 5,
+			prev:  before(5),
+		},
+		{
+			name:  "last row on the boundary of the page",
+			query: models.AlbumQuery{Limit: 2, Cursor: after(3)},
+			want:  []int{4, 5},
+			total: 5,
+			prev:  before(4),
+		},
+		{
+			name:  "all rows in the first page",
+			query: models.AlbumQuery{Limit: 5},
+			want:  []int{1, 2, 3, 4, 5},
+			total: 5,
+		},
+		{
+			name:  "ascending previous page",
+			query: models.AlbumQuery{Limit: 2, Cursor: before(4)},
+			want:  []int{2, 3},
+			total: 5,
+			next:  after(3),
+			prev:  before(2),
+		},
+		{
+			name:  "descending first page",
+			query: models.AlbumQuery{Descending: true, Limit: 2},
+			want:  []int{5, 4},
+			total: 5,
+			next:  after(4),
+		},
+		{
+			name:  "descending last page",
+			query: models.AlbumQuery{Descending: true, Limit: 2, Cursor: after(2)},
+			want:  []int{1},
+			total: 5,
+			prev:  before(1),
+		},
+		{
+			name:  "descending previous page to the first one",
+			query: models.AlbumQuery{Descending: true, Limit: 2, Cursor: before(3)},
+			want:  []int{5, 4},
+			total: 5,
+			next:  after(4),
+		},
+		{
+			name:  "range of dates",
+			query: models.AlbumQuery{From: day(2), To: day(4), Limit: 2},
+			want:  []int{2, 3},
+			total: 3,
+			next:  after(3),
+		},
+		{
+			name:  "cursor after the range",
+			query: models.AlbumQuery{To: day(3), Limit: 2, Cursor: after(3)},
+			want:  nil,
+			total: 3,
+		},
+	}
+
+	repo := &albumRepo{dates: []time.Time{day(1), day(2), day(3), day(4), day(5)}}
+	s := New(nil, repo, nil, nil, Config{})
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := s.GetAlbum(context.Background(), tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []int
+			for _, r := range page.Records {
+				got = append(got, r.Date.Day())
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("dates = %v, want %v", got, tc.want)
+			}
+
+			if page.Total != tc.total {
+				t.Errorf("total = %v, want %v", page.Total, tc.total)
+			}
+
+			if !reflect.DeepEqual(page.Next, tc.next) {
+				t.Errorf("next = %+v, want %+v", page.Next, tc.next)
+			}
+
+			if !reflect.DeepEqual(page.Prev, tc.prev) {
+				t.Errorf("prev = %+v, want %+v", page.Prev, tc.prev)
+			}
+		})
+	}
+}
+
+func TestGetAlbumDefaultLimit(t *testing.T) {
+	repo := &albumRepo{}
+	for d := day(1); len(repo.dates) < defaultAlbumLimit+1; d = d.AddDate(0, 0, 1) {
+		repo.dates = append(repo.dates, d)
+	}
+
+	s := New(nil, repo, nil, nil, Config{})
+
+	page, err := s.GetAlbum(context.Background(), models.AlbumQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Records) != defaultAlbumLimit || page.Next == nil {
+		t.Errorf("records = %v, next = %v, want %v records and the next page", len(page.Records), page.Next, defaultAlbumLimit)
+	}
+}
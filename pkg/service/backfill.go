@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Backfill downloads and saves images of all dates between from and to inclusive which are not stored yet.
+// Descriptions of the dates are fetched by a single request to the APOD api,
+// images are downloaded by at most concurrency goroutines at once.
+// Zero to means the current APOD date, dates of the range are validated like in GetImageForDate.
+// Returned error joins errors of all failed dates.
+func (s *Service) Backfill(ctx context.Context, from, to time.Time, concurrency int) error {
+	now := time.Now()
+	if to.IsZero() {
+		to = s.calendar.today(now)
+	}
+
+	if to.Before(from) {
+		return fmt.Errorf("invalid range: %v is before %v", to, from)
+	}
+
+	if err := s.calendar.validate(from, now); err != nil {
+		return fmt.Errorf("invalid range start: %w", err)
+	}
+
+	if err := s.calendar.validate(to, now); err != nil {
+		return fmt.Errorf("invalid range end: %w", err)
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	stored, err := s.repo.FetchDatesInRange(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("fetch stored dates: %w", err)
+	}
+
+	storedDates := make(map[string]struct{}, len(stored))
+	for _, d := range stored {
+		storedDates[d.Format(time.DateOnly)] = struct{}{}
+	}
+
+	apods, err := s.downloader.apod.GetAPODsForRange(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("get apods for range: %w", err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mx   sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, concurrency)
+	)
+
+	for i := range apods {
+		apod := apods[i]
+		if _, ok := storedDates[apod.Date.Format(time.DateOnly)]; ok {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := s.downloadImage(ctx, apod.Date, &apod); err != nil {
+				mx.Lock()
+				errs = append(errs, fmt.Errorf("date %v: %w", apod.Date.Format(time.DateOnly), err))
+				mx.Unlock()
+
+				return
+			}
+
+			logrus.Infof("backfill: saved %v", apod.Date.Format(time.DateOnly))
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
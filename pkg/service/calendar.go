@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+// calendar knows when the APODs are published, days of APOD roll over in its location.
+// APOD of the date is published at hour:minute of the date in the location.
+type calendar struct {
+	location *time.Location
+	hour     int
+	minute   int
+}
+
+// publishTime returns the expected time of the publication of the APOD of the date.
+func (c calendar) publishTime(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), c.hour, c.minute, 0, 0, c.location)
+}
+
+// today returns the date of the latest APOD which is expected to be published at the moment now.
+func (c calendar) today(now time.Time) time.Time {
+	local := now.In(c.location)
+	date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	if now.Before(c.publishTime(date)) {
+		date = date.AddDate(0, 0, -1)
+	}
+
+	return date
+}
+
+// validate checks that the APOD of the date can be published at the moment now.
+// Dates before the first APOD and after the next day are out of range,
+// for the next day *models.NotPublishedError with the expected publication time is returned.
+func (c calendar) validate(date, now time.Time) error {
+	if date.Before(models.FirstAPODDate) {
+		return fmt.Errorf("%w: %v is before the first apod", models.ErrDateOutOfRange, date.Format(time.DateOnly))
+	}
+
+	today := c.today(now)
+	if !date.After(today) {
+		return nil
+	}
+
+	if date.Equal(today.AddDate(0, 0, 1)) {
+		return &models.NotPublishedError{Date: date, PublishAt: c.publishTime(date)}
+	}
+
+	return fmt.Errorf("%w: %v is in future", models.ErrDateOutOfRange, date.Format(time.DateOnly))
+}
+
+// retryAfter limits the time of the next download attempt of the current or the next date.
+// Before the publication it is retried at the publication time, after it at most after recent,
+// so the failure of the early request does not block the date after the publication.
+func (c calendar) retryAfter(date, now, retryAt time.Time, recent time.Duration) time.Time {
+	if date.Before(c.today(now)) {
+		return retryAt
+	}
+
+	limit := c.publishTime(date)
+	if !limit.After(now) {
+		limit = now.Add(recent)
+	}
+
+	if retryAt.After(limit) {
+		return limit
+	}
+
+	return retryAt
+}
+
+// notPublished converts the error of the download of the current date, which NASA has not published yet,
+// to *models.NotPublishedError expected after retry. Errors of other dates are returned as is.
+func (c calendar) notPublished(date, now time.Time, err error, retry time.Duration) error {
+	if !date.Equal(c.today(now)) ||
+		!(errors.Is(err, models.ErrAPODNotFound) || errors.Is(err, models.ErrDateOutOfRange)) {
+		return err
+	}
+
+	publishAt := now.Add(retry)
+
+	var failureErr *models.FailureError
+	if errors.As(err, &failureErr) && !failureErr.Failure.RetryAfter.IsZero() {
+		publishAt = failureErr.Failure.RetryAfter
+	}
+
+	return &models.NotPublishedError{Date: date, PublishAt: publishAt}
+}
@@ -2,47 +2,122 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/Dyleme/apod.git/pkg/models"
+	"github.com/sirupsen/logrus"
 )
 
+// downloaders runs downloads under its own lifecycle context,
+// so a download is not aborted when the request which started it is canceled.
 type downloaders struct {
 	mx      sync.Mutex
 	waiters map[time.Time][]chan<- error
+	closed  bool
+	running sync.WaitGroup
+
+	ctx      context.Context //nolint:containedctx // lifecycle context of the downloads
+	cancel   context.CancelFunc
+	timeout  time.Duration
+	widths   []int
+	decodes  chan struct{}
+	retry    time.Duration
+	recent   time.Duration
+	calendar calendar
+
 	apod    APODer
 	storage Storager
 	repo    Repository
 }
 
-func (d *downloaders) download(ctx context.Context, date time.Time) {
-	err := d.downloadAndSaveImage(ctx, date)
+func (d *downloaders) download(date time.Time, apod *models.APOD) {
+	defer d.running.Done()
+
+	ctx, cancel := context.WithTimeout(d.ctx, d.timeout)
+	defer cancel()
+
+	failure, err := d.repo.FetchFailure(ctx, date)
+	if err != nil && !errors.Is(err, models.ErrFailureNotExists) {
+		logrus.Errorf("fetch failure of %v: %v", date.Format(time.DateOnly), err)
+	}
+
+	if failure != nil && failure.Active(time.Now()) {
+		d.sendErr(&models.FailureError{Failure: *failure}, date)
+
+		return
+	}
+
+	record, err := d.downloadAndSaveImage(ctx, date, apod)
+	d.recordFailure(d.ctx, date, err, failure != nil)
 	d.sendErr(err, date)
-}
 
-func (d *downloaders) downloadAndSaveImage(ctx context.Context, date time.Time) error {
-	image, ext, err := d.apod.GetImageForDate(ctx, date)
 	if err != nil {
-		return fmt.Errorf("get image from date %v: %w", date, err)
+		return
+	}
+
+	// waiters are released with the stored record, renditions are listed once they are stored.
+	// They have own timeout, because they can wait for other images to be decoded.
+	rendCtx, rendCancel := context.WithTimeout(d.ctx, d.timeout)
+	defer rendCancel()
+
+	if err := d.generateRenditions(rendCtx, record); err != nil {
+		logrus.Errorf("renditions of %v: %v", date.Format(time.DateOnly), err)
+	}
+}
+
+// downloadAndSaveImage downloads images of the date and saves them.
+// If the apod is provided its description is not fetched again.
+// Images are spooled to temporary files without buffering them in memory.
+// Renditions of the returned record are not generated.
+func (d *downloaders) downloadAndSaveImage(ctx context.Context, date time.Time, apod *models.APOD) (*models.AlbumRecord, error) {
+	var record models.AlbumRecord
+
+	save := func(ctx context.Context, img *models.Image) error {
+		ref, info, err := d.saveImage(ctx, img)
+		if err != nil {
+			return err
+		}
+
+		switch img.Quality {
+		case models.QualityHD:
+			record.HDImage = ref
+			record.HDImageFile = *info
+		case models.QualityStandard:
+			record.Image = ref
+			record.ImageFile = *info
+		}
+
+		return nil
 	}
 
-	filename := uuid.NewString() + ext
+	var err error
+
+	// Videos can have no thumbnail, they are saved without images.
+	if apod == nil {
+		apod, err = d.apod.GetImageForDate(ctx, date, save)
+	} else {
+		err = d.apod.GetImagesForAPOD(ctx, apod, save)
+	}
 
-	path, err := d.storage.UploadFile(ctx, imageBucket, filename, image)
 	if err != nil {
-		return fmt.Errorf("upload file bucket[%q], filename[%q], len(image)[%v]:%w", imageBucket, filename, len(image), err)
+		return nil, fmt.Errorf("get image from date %v: %w", date, err)
 	}
 
-	err = d.repo.AddImage(ctx, date, path)
+	record.APOD = *apod
+
+	err = d.repo.AddImage(ctx, &record)
 	if err != nil {
-		return fmt.Errorf("set image url %q: %w", path, err)
+		return nil, fmt.Errorf("add image %q: %w", record.Image.Key, err)
 	}
 
-	return nil
+	return &record, nil
 }
 
+// sendErr sends the result of the download to all waiters of the date.
+// Waiters channels should be buffered, because waiters can stop waiting before the download ends.
 func (ds *downloaders) sendErr(err error, date time.Time) {
 	ds.mx.Lock()
 	defer ds.mx.Unlock()
@@ -55,14 +130,50 @@ func (ds *downloaders) sendErr(err error, date time.Time) {
 
 // registerDownloadWaiter function is used to start downloading and saving images.
 // After image saving completes waiter will receive downloading error.
-func (d *downloaders) registerDownloadWaiter(ctx context.Context, date time.Time, waiter chan<- error) {
+// Already fetched description of the date can be provided by apod argument, otherwise it should be nil.
+// If downloaders are closed waiter receives models.ErrServiceClosed immediately.
+func (d *downloaders) registerDownloadWaiter(date time.Time, apod *models.APOD, waiter chan<- error) {
 	d.mx.Lock()
 	defer d.mx.Unlock()
 
+	if d.closed {
+		waiter <- models.ErrServiceClosed
+
+		return
+	}
+
 	if _, ok := d.waiters[date]; !ok {
 		d.waiters[date] = make([]chan<- error, 0, 2)
-		go d.download(ctx, date)
+		d.running.Add(1)
+		go d.download(date, apod)
 	}
 
 	d.waiters[date] = append(d.waiters[date], waiter)
 }
+
+// close forbids new downloads and waits for the running ones to complete.
+// If ctx is done before, running downloads are canceled.
+func (d *downloaders) close(ctx context.Context) error {
+	d.mx.Lock()
+	d.closed = true
+	d.mx.Unlock()
+
+	drained := make(chan struct{})
+
+	go func() {
+		d.running.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		d.cancel()
+
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-drained
+
+		return fmt.Errorf("downloads canceled: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// recentPeriod is the age of the dates which are not published yet or published just now,
+// NASA can answer that they do not exist, so their failures are not permanent.
+const recentPeriod = 48 * time.Hour
+
+// apiKeyRe matches the api key in the urls of the requests to NASA.
+var apiKeyRe = regexp.MustCompile(`(api_key=)[^&\s"]+`)
+
+// recordFailure records the failure of the download of the date, so NASA is not called again for it.
+// After the successful download the previous failure is removed if the date had one.
+func (d *downloaders) recordFailure(ctx context.Context, date time.Time, downErr error, hadFailure bool) {
+	if downErr == nil {
+		if !hadFailure {
+			return
+		}
+
+		if err := d.repo.DeleteFailure(ctx, date); err != nil && !errors.Is(err, models.ErrFailureNotExists) {
+			logrus.Errorf("delete failure of %v: %v", date.Format(time.DateOnly), err)
+		}
+
+		return
+	}
+
+	now := time.Now()
+
+	failure := newFailure(date, downErr, now, d.retry)
+	if failure == nil {
+		return
+	}
+
+	if !failure.Permanent {
+		failure.RetryAfter = d.calendar.retryAfter(date, now, failure.RetryAfter, d.recent)
+	}
+
+	if err := d.repo.AddFailure(ctx, failure); err != nil {
+		logrus.Errorf("add failure of %v: %v", date.Format(time.DateOnly), err)
+	}
+}
+
+// newFailure returns the failure of the download of the date at the moment now.
+// Nil is returned for the errors which do not depend on the date, for example rate limiting or storage errors.
+func newFailure(date time.Time, err error, now time.Time, retry time.Duration) *models.DownloadFailure {
+	var (
+		reason    models.FailureReason
+		permanent bool
+	)
+
+	switch {
+	case errors.Is(err, models.ErrAPODNotFound):
+		reason, permanent = models.FailureNotFound, true
+	case errors.Is(err, models.ErrDateOutOfRange):
+		reason, permanent = models.FailureDateOutOfRange, true
+	case errors.Is(err, models.ErrUnsupportedMediaType),
+		errors.Is(err, models.ErrDisallowedContentType),
+		errors.Is(err, models.ErrFileTooLarge):
+		reason, permanent = models.FailureUnsupportedMedia, true
+	case errors.Is(err, models.ErrUpstreamUnavailable):
+		reason = models.FailureUpstreamUnavailable
+	default:
+		return nil
+	}
+
+	if date.After(now.Add(-recentPeriod)) {
+		permanent = false
+	}
+
+	failure := &models.DownloadFailure{
+		Date:      date,
+		Reason:    reason,
+		Message:   redactAPIKey(err.Error()),
+		Permanent: permanent,
+		FailedAt:  now,
+	}
+
+	if !permanent {
+		failure.RetryAfter = now.Add(retry)
+	}
+
+	return failure
+}
+
+// redactAPIKey hides values of the api_key query parameters in the message of the error.
+func redactAPIKey(msg string) string {
+	return apiKeyRe.ReplaceAllString(msg, "${1}REDACTED")
+}
+
+// ClearFailure removes the recorded failure of the date, so the next request downloads it again.
+// If the date has no failure models.ErrFailureNotExists is returned.
+func (s *Service) ClearFailure(ctx context.Context, date time.Time) error {
+	if err := s.repo.DeleteFailure(ctx, date); err != nil {
+		return fmt.Errorf("delete failure: %w", err)
+	}
+
+	return nil
+}
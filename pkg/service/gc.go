@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+// CollectGarbage removes files of the image bucket which are not referenced by any record.
+// Only files older than the grace period are removed, so the files of the running downloads are kept.
+// In the dry run mode files are only reported.
+func (s *Service) CollectGarbage(ctx context.Context, grace time.Duration, dryRun bool) (*models.GCReport, error) {
+	files, err := s.storage.ListFiles(ctx, imageBucket)
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+
+	refs, err := s.repo.FetchImageKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch image keys: %w", err)
+	}
+
+	referenced := make(map[models.ObjectRef]struct{}, len(refs))
+	for _, ref := range refs {
+		referenced[ref] = struct{}{}
+	}
+
+	report := &models.GCReport{Scanned: len(files), DryRun: dryRun}
+	deadline := time.Now().Add(-grace)
+
+	var errs []error
+
+	for _, f := range files {
+		if _, ok := referenced[models.ObjectRef{Bucket: imageBucket, Key: f.Name}]; ok {
+			continue
+		}
+
+		if f.LastModified.After(deadline) {
+			continue
+		}
+
+		report.Orphaned = append(report.Orphaned, f.Name)
+
+		if dryRun {
+			continue
+		}
+
+		// the file could be reused by the download after it was listed, reused files are touched.
+		stat, err := s.storage.StatFile(ctx, imageBucket, f.Name)
+		if errors.Is(err, models.ErrFileNotExists) {
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("stat %q: %w", f.Name, err))
+
+			continue
+		}
+
+		if stat.LastModified.After(deadline) {
+			continue
+		}
+
+		if err := s.storage.DeleteFile(ctx, imageBucket, f.Name); err != nil {
+			errs = append(errs, fmt.Errorf("delete %q: %w", f.Name, err))
+
+			continue
+		}
+
+		report.Deleted++
+	}
+
+	return report, errors.Join(errs...)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+// objectKey returns the content addressed key of the file: sha256/ab/cd/abcd...ext.
+func objectKey(sum, ext string) string {
+	return path.Join("sha256", sum[0:2], sum[2:4], sum+ext)
+}
+
+// saveImage stores the image under the key derived from its SHA-256 hash.
+// The image is spooled to the temporary file while the hash is computed.
+// If the object with the same content already exists the upload is skipped and the object is touched,
+// so the garbage collection does not remove it as an old unreferenced file before the record is added.
+func (d *downloaders) saveImage(ctx context.Context, img *models.Image) (models.ObjectRef, *models.FileInfo, error) {
+	tmp, err := os.CreateTemp("", "apod-*")
+	if err != nil {
+		return models.ObjectRef{}, nil, fmt.Errorf("create temp file: %w", err)
+	}
+
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	hash := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(tmp, hash), img.Body)
+	if err != nil {
+		return models.ObjectRef{}, nil, fmt.Errorf("download image: %w", err)
+	}
+
+	info := &models.FileInfo{
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		Size:        size,
+		ContentType: img.ContentType,
+	}
+	filename := objectKey(info.SHA256, img.Extension)
+	ref := models.ObjectRef{Bucket: imageBucket, Key: filename}
+
+	err = d.storage.TouchFile(ctx, imageBucket, filename)
+	if err == nil {
+		return ref, info, nil
+	}
+
+	if !errors.Is(err, models.ErrFileNotExists) {
+		return models.ObjectRef{}, nil, fmt.Errorf("touch file %q: %w", filename, err)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return models.ObjectRef{}, nil, fmt.Errorf("seek temp file: %w", err)
+	}
+
+	err = d.storage.UploadFile(ctx, imageBucket, filename, tmp, size)
+	if err != nil {
+		return models.ObjectRef{}, nil, fmt.Errorf("upload file bucket[%q], filename[%q], size[%v]: %w", imageBucket, filename, size, err)
+	}
+
+	return ref, info, nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+const randomConcurrency = 4
+
+// GetRandomImages returns random records, see models.RandomQuery.
+// Stored records are returned by default, if there are less of them than requested all of them are returned.
+// Fresh entries are fetched from NASA only if it is enabled by the config,
+// otherwise models.ErrRandomFromNASADisabled is returned.
+func (s *Service) GetRandomImages(ctx context.Context, query models.RandomQuery) ([]models.AlbumRecord, error) {
+	if query.Count < 1 {
+		query.Count = 1
+	}
+
+	if query.Fresh {
+		if !s.randomNASA {
+			return nil, models.ErrRandomFromNASADisabled
+		}
+
+		if query.Count > models.MaxRandomFromNASA {
+			query.Count = models.MaxRandomFromNASA
+		}
+
+		return s.randomFromNASA(ctx, query.Count)
+	}
+
+	return s.randomStored(ctx, query.Count, query.Seed)
+}
+
+// randomStored picks count stored records by the random generator with the seed.
+func (s *Service) randomStored(ctx context.Context, count int, seed int64) ([]models.AlbumRecord, error) {
+	dates, err := s.repo.FetchDatesInRange(ctx, models.FirstAPODDate, s.calendar.today(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("fetch stored dates: %w", err)
+	}
+
+	// dates are sorted, so the picks depend only on the seed and the stored dates.
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	if count > len(dates) {
+		count = len(dates)
+	}
+
+	rnd := rand.New(rand.NewSource(seed)) //nolint:gosec // picks do not need secure random
+	for i := 0; i < count; i++ {
+		j := i + rnd.Intn(len(dates)-i)
+		dates[i], dates[j] = dates[j], dates[i]
+	}
+
+	picked := dates[:count]
+
+	stored, err := s.repo.FetchImagesByDates(ctx, picked)
+	if err != nil {
+		return nil, fmt.Errorf("fetch images: %w", err)
+	}
+
+	byDate := make(map[time.Time]models.AlbumRecord, len(stored))
+	for _, record := range stored {
+		byDate[record.Date] = record
+	}
+
+	records := make([]models.AlbumRecord, 0, count)
+
+	for _, date := range picked {
+		record, ok := byDate[date]
+		if !ok {
+			continue
+		}
+
+		if err := s.setReadURLs(ctx, &record); err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// randomFromNASA fetches count random entries from NASA and stores the ones which are not stored yet,
+// at most randomConcurrency entries are downloaded at once.
+// Entries which fail to download are skipped, the error is returned only if all of them failed.
+func (s *Service) randomFromNASA(ctx context.Context, count int) ([]models.AlbumRecord, error) {
+	apods, err := s.downloader.apod.GetRandomAPODs(ctx, count)
+	if err != nil {
+		return nil, fmt.Errorf("get random apods: %w", err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, randomConcurrency)
+		downErrs = make([]error, len(apods))
+	)
+
+	for i := range apods {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+
+			return nil, ctx.Err()
+		}
+
+		wg.Add(1)
+
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			apod := apods[i]
+
+			_, err := s.repo.FetchImage(ctx, apod.Date)
+			if errors.Is(err, models.ErrImageNotExists) {
+				err = s.downloadImage(ctx, apod.Date, &apod)
+			}
+
+			downErrs[i] = err
+		}(i)
+	}
+
+	wg.Wait()
+
+	var (
+		dates []time.Time
+		errs  []error
+	)
+
+	for i, apod := range apods {
+		if downErrs[i] != nil {
+			logrus.Errorf("random: %v: %v", apod.Date.Format(time.DateOnly), downErrs[i])
+			errs = append(errs, fmt.Errorf("date %v: %w", apod.Date.Format(time.DateOnly), downErrs[i]))
+
+			continue
+		}
+
+		dates = append(dates, apod.Date)
+	}
+
+	if len(dates) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	records, err := s.repo.FetchImagesByDates(ctx, dates)
+	if err != nil {
+		return nil, fmt.Errorf("fetch images: %w", err)
+	}
+
+	for i := range records {
+		if err := s.setReadURLs(ctx, &records[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+	"github.com/Dyleme/apod.git/pkg/rendition"
+	"github.com/sirupsen/logrus"
+)
+
+// maxRenditionDecodes limits the number of images decoded at once by the process,
+// decoded image can take up to 400MB of memory.
+const maxRenditionDecodes = 2
+
+// renditionSource returns the largest image of the record if its renditions can be generated.
+func renditionSource(record *models.AlbumRecord) (models.ObjectRef, bool) {
+	source, info := record.ImageObject(models.QualityHD)
+
+	return source, !source.IsZero() && rendition.Supported(info.ContentType)
+}
+
+// generateRenditions generates renditions of the largest image of the record, stores them and replaces stored ones.
+// Images which format can not be decoded are skipped.
+func (d *downloaders) generateRenditions(ctx context.Context, record *models.AlbumRecord) error {
+	source, ok := renditionSource(record)
+	if len(d.widths) == 0 || !ok {
+		return nil
+	}
+
+	file, err := d.storage.OpenFile(ctx, source.Bucket, source.Key)
+	if err != nil {
+		return fmt.Errorf("open source %q: %w", source.Key, err)
+	}
+	defer file.Close()
+
+	select {
+	case d.decodes <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	generated, err := rendition.Generate(file, d.widths)
+	<-d.decodes
+
+	if err != nil {
+		return fmt.Errorf("generate: %w", err)
+	}
+
+	renditions := make([]models.Rendition, 0, len(generated))
+
+	for _, g := range generated {
+		sum := sha256.Sum256(g.Data)
+		hash := hex.EncodeToString(sum[:])
+
+		rend := models.Rendition{
+			Width:  g.Width,
+			Height: g.Height,
+			Object: models.ObjectRef{Bucket: source.Bucket, Key: objectKey(hash, rendition.Extension)},
+			File: models.FileInfo{
+				SHA256:      hash,
+				Size:        int64(len(g.Data)),
+				ContentType: rendition.ContentType,
+			},
+		}
+
+		if err := d.storeRendition(ctx, rend.Object, g.Data); err != nil {
+			return err
+		}
+
+		renditions = append(renditions, rend)
+	}
+
+	if err := d.repo.SetRenditions(ctx, record.Date, renditions); err != nil {
+		return fmt.Errorf("set renditions: %w", err)
+	}
+
+	record.Renditions = renditions
+
+	return nil
+}
+
+// storeRendition uploads the rendition, if the same content is already stored it is touched like in saveImage.
+func (d *downloaders) storeRendition(ctx context.Context, ref models.ObjectRef, data []byte) error {
+	err := d.storage.TouchFile(ctx, ref.Bucket, ref.Key)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, models.ErrFileNotExists) {
+		return fmt.Errorf("touch rendition %q: %w", ref.Key, err)
+	}
+
+	err = d.storage.UploadFile(ctx, ref.Bucket, ref.Key, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("upload rendition %q: %w", ref.Key, err)
+	}
+
+	return nil
+}
+
+// GenerateRenditions generates renditions of the stored images by at most concurrency goroutines at once.
+// If all is false only images without renditions are processed, otherwise renditions of all images are replaced.
+// Images in the formats which can not be decoded, like webp, are skipped.
+// Returns the number of processed images, the error joins errors of all failed dates.
+func (s *Service) GenerateRenditions(ctx context.Context, all bool, concurrency int) (int, error) {
+	if len(s.downloader.widths) == 0 {
+		return 0, errors.New("rendition widths are not configured")
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	album, err := s.repo.FetchAlbum(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("fetch album: %w", err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mx        sync.Mutex
+		errs      []error
+		processed int
+		sem       = make(chan struct{}, concurrency)
+	)
+
+	for i := range album {
+		record := &album[i]
+		if _, ok := renditionSource(record); !ok || (!all && len(record.Renditions) > 0) {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+
+			return processed, errors.Join(append(errs, ctx.Err())...)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			err := s.downloader.generateRenditions(ctx, record)
+
+			mx.Lock()
+			defer mx.Unlock()
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("date %v: %w", record.Date.Format(time.DateOnly), err))
+
+				return
+			}
+
+			processed++
+
+			logrus.Infof("renditions: generated %v for %v", len(record.Renditions), record.Date.Format(time.DateOnly))
+		}()
+	}
+
+	wg.Wait()
+
+	return processed, errors.Join(errs...)
+}
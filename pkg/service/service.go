@@ -4,6 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,91 +16,513 @@ import (
 
 const (
 	imageBucket = "images"
+
+	defaultDownloadTimeout = 2 * time.Minute
+	defaultGCGracePeriod   = 24 * time.Hour
+	defaultFailureRetry    = time.Hour
+	defaultSyncRetry       = 5 * time.Minute
+	defaultPublishTime     = "00:00"
+	defaultPublishTimezone = "America/New_York"
+	defaultAlbumLimit      = 50
 )
 
+// defaultRenditionWidths are the widths of the renditions which are generated if they are not configured.
+var defaultRenditionWidths = []int{256, 1024, 2048}
+
+// Config is a config of the Service.
+// DownloadTimeout limits the time of the downloading and saving images of one date.
+// GCInterval is the interval of the scheduled garbage collection of the storage, zero disables it.
+// GCGracePeriod is the age of the unreferenced files after which they are removed.
+// If MediaBaseURL is set links to images point to the application media route at MediaBaseURL/media/{date}.
+// RenditionWidths are the widths of the resized copies generated for every stored image, empty disables them.
+// FailureRetry is the time after which the date which download failed transiently is downloaded again.
+// Failures of the current date are retried after at most SyncRetryInterval or at its publication time,
+// so a failure before the publication does not block the date for FailureRetry.
+// SyncRetryInterval is also the interval between attempts of the daily sync to fetch the current APOD.
+// APOD of the date is expected to be published at PublishHour:PublishMinute of the date in PublishLocation,
+// UTC is used if the location is not set.
+// RandomFromNASA allows requests of random entries to fetch them from NASA, every such request spends the rate limit.
+type Config struct {
+	DownloadTimeout   time.Duration
+	GCInterval        time.Duration
+	GCGracePeriod     time.Duration
+	MediaBaseURL      string
+	RenditionWidths   []int
+	FailureRetry      time.Duration
+	SyncRetryInterval time.Duration
+	PublishLocation   *time.Location
+	PublishHour       int
+	PublishMinute     int
+	RandomFromNASA    bool
+}
+
+func InitConfig() (*Config, error) {
+	timeout, err := getEnvDuration("DOWNLOAD_TIMEOUT", defaultDownloadTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	gcInterval, err := getEnvDuration("GC_INTERVAL", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	gcGrace, err := getEnvDuration("GC_GRACE_PERIOD", defaultGCGracePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	failureRetry, err := getEnvDuration("FAILURE_RETRY", defaultFailureRetry)
+	if err != nil {
+		return nil, err
+	}
+
+	syncRetry, err := getEnvDuration("SYNC_RETRY_INTERVAL", defaultSyncRetry)
+	if err != nil {
+		return nil, err
+	}
+
+	publishTime := os.Getenv("PUBLISH_TIME")
+	if publishTime == "" {
+		publishTime = defaultPublishTime
+	}
+
+	publishAt, err := time.Parse("15:04", publishTime)
+	if err != nil {
+		return nil, fmt.Errorf("cant parse publish time %q: %w", publishTime, err)
+	}
+
+	timezone := os.Getenv("PUBLISH_TIMEZONE")
+	if timezone == "" {
+		timezone = defaultPublishTimezone
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("cant load publish timezone %q: %w", timezone, err)
+	}
+
+	var randomFromNASA bool
+	if v := os.Getenv("RANDOM_FROM_NASA"); v != "" {
+		randomFromNASA, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("cant parse RANDOM_FROM_NASA %q: %w", v, err)
+		}
+	}
+
+	widths := defaultRenditionWidths
+	if v, ok := os.LookupEnv("RENDITION_WIDTHS"); ok {
+		widths, err = parseWidths(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Config{
+		DownloadTimeout:   timeout,
+		GCInterval:        gcInterval,
+		GCGracePeriod:     gcGrace,
+		MediaBaseURL:      strings.TrimSuffix(os.Getenv("MEDIA_BASE_URL"), "/"),
+		RenditionWidths:   widths,
+		FailureRetry:      failureRetry,
+		SyncRetryInterval: syncRetry,
+		PublishLocation:   loc,
+		PublishHour:       publishAt.Hour(),
+		PublishMinute:     publishAt.Minute(),
+		RandomFromNASA:    randomFromNASA,
+	}, nil
+}
+
+// parseWidths parses comma separated positive widths, empty string is parsed to no widths.
+func parseWidths(v string) ([]int, error) {
+	var widths []int
+
+	for _, w := range strings.Split(v, ",") {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			continue
+		}
+
+		width, err := strconv.Atoi(w)
+		if err != nil || width <= 0 {
+			return nil, fmt.Errorf("invalid rendition width %q", w)
+		}
+
+		widths = append(widths, width)
+	}
+
+	return widths, nil
+}
+
+func getEnvDuration(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("cant parse %s %q: %w", key, v, err)
+	}
+
+	return d, nil
+}
+
 type APODer interface {
-	GetImageForDate(ctx context.Context, date time.Time) (img []byte, extension string, err error)
+	GetImageForDate(ctx context.Context, date time.Time, save models.SaveImageFunc) (*models.APOD, error)
+	GetImagesForAPOD(ctx context.Context, apod *models.APOD, save models.SaveImageFunc) error
+	GetAPODsForRange(ctx context.Context, start, end time.Time) ([]models.APOD, error)
+	GetRandomAPODs(ctx context.Context, count int) ([]models.APOD, error)
 }
 
 type Repository interface {
-	AddImage(ctx context.Context, date time.Time, path string) error
-	FetchImagePath(ctx context.Context, date time.Time) (string, error)
+	AddImage(ctx context.Context, record *models.AlbumRecord) error
+	FetchImage(ctx context.Context, date time.Time) (*models.AlbumRecord, error)
 	FetchAlbum(ctx context.Context) ([]models.AlbumRecord, error)
+	FetchImagesByDates(ctx context.Context, dates []time.Time) ([]models.AlbumRecord, error)
+	FetchAlbumPage(ctx context.Context, from, to time.Time, desc bool, limit int) ([]models.AlbumRecord, error)
+	CountAlbum(ctx context.Context, from, to time.Time) (int, error)
+	FetchDatesInRange(ctx context.Context, from, to time.Time) ([]time.Time, error)
+	FetchImageKeys(ctx context.Context) ([]models.ObjectRef, error)
+	SetRenditions(ctx context.Context, date time.Time, renditions []models.Rendition) error
+	AddFailure(ctx context.Context, failure *models.DownloadFailure) error
+	// FetchFailure and DeleteFailure return models.ErrFailureNotExists if there is no failure of the date.
+	FetchFailure(ctx context.Context, date time.Time) (*models.DownloadFailure, error)
+	DeleteFailure(ctx context.Context, date time.Time) error
 }
 
 type Storager interface {
-	UploadFile(ctx context.Context, bucket, filename string, data []byte) (url string, err error)
+	// UploadFile uploads the data to the storage, size is -1 if the length of the data is unknown.
+	// The service always passes the known size, because the hash of the content is computed before the upload.
+	UploadFile(ctx context.Context, bucket, filename string, data io.Reader, size int64) error
+	// StatFile returns models.ErrFileNotExists if there is no such file.
+	StatFile(ctx context.Context, bucket, filename string) (*models.FileInfo, error)
+	ListFiles(ctx context.Context, bucket string) ([]models.FileInfo, error)
+	// OpenFile returns models.ErrFileNotExists if there is no such file.
+	// The file is seekable, so its ranges can be read.
+	OpenFile(ctx context.Context, bucket, filename string) (io.ReadSeekCloser, error)
+	// TouchFile sets the modification time of the file to the current time,
+	// it returns models.ErrFileNotExists if there is no such file.
+	TouchFile(ctx context.Context, bucket, filename string) error
+	DeleteFile(ctx context.Context, bucket, filename string) error
+}
+
+// URLBuilder builds the links by which clients read the stored files, links can expire.
+type URLBuilder interface {
+	URL(ctx context.Context, bucket, filename string) (string, error)
 }
 
 type Service struct {
-	repo       Repository
-	downloader downloaders
+	repo         Repository
+	storage      Storager
+	urls         URLBuilder
+	mediaBaseURL string
+	calendar     calendar
+	failureRetry time.Duration
+	randomNASA   bool
+	downloader   downloaders
 }
 
-func New(apod APODer, repo Repository, storage Storager) *Service {
+// New is a constructor to the Service.
+// Zero DownloadTimeout and SyncRetryInterval are replaced by the defaults, nil PublishLocation by UTC.
+func New(apod APODer, repo Repository, storage Storager, urls URLBuilder, cfg Config) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	loc := cfg.PublishLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	timeout := cfg.DownloadTimeout
+	if timeout <= 0 {
+		timeout = defaultDownloadTimeout
+	}
+
+	syncRetry := cfg.SyncRetryInterval
+	if syncRetry <= 0 {
+		syncRetry = defaultSyncRetry
+	}
+
+	cal := calendar{location: loc, hour: cfg.PublishHour, minute: cfg.PublishMinute}
+
 	return &Service{
-		repo: repo,
+		repo:         repo,
+		storage:      storage,
+		urls:         urls,
+		mediaBaseURL: cfg.MediaBaseURL,
+		calendar:     cal,
+		failureRetry: cfg.FailureRetry,
+		randomNASA:   cfg.RandomFromNASA,
 		downloader: downloaders{
-			mx:      sync.Mutex{},
-			waiters: make(map[time.Time][]chan<- error),
-			apod:    apod,
-			storage: storage,
-			repo:    repo,
+			mx:       sync.Mutex{},
+			waiters:  make(map[time.Time][]chan<- error),
+			ctx:      ctx,
+			cancel:   cancel,
+			timeout:  timeout,
+			widths:   cfg.RenditionWidths,
+			decodes:  make(chan struct{}, maxRenditionDecodes),
+			retry:    cfg.FailureRetry,
+			recent:   syncRetry,
+			calendar: cal,
+			apod:     apod,
+			storage:  storage,
+			repo:     repo,
 		},
 	}
 }
 
-func (s *Service) GetImageURLForDate(ctx context.Context, date time.Time) (string, error) {
-	var (
-		url string
-		err error
-	)
+// CurrentDate returns the date of the latest APOD which is expected to be published at the moment now.
+func (s *Service) CurrentDate(now time.Time) time.Time {
+	return s.calendar.today(now)
+}
+
+// PublishTime returns the expected time of the publication of the APOD of the date.
+func (s *Service) PublishTime(date time.Time) time.Time {
+	return s.calendar.publishTime(date)
+}
 
-	url, err = s.repo.FetchImagePath(ctx, date)
+// Close method stops accepting new downloads and waits for the running ones.
+// If ctx is done before downloads complete, they are canceled.
+func (s *Service) Close(ctx context.Context) error {
+	return s.downloader.close(ctx)
+}
+
+// GetImageForDate returns the stored record of the date, downloading it if it is not stored yet.
+// Dates which can not be published yet are rejected without calling NASA, see calendar.validate.
+// If NASA has not published the APOD of the current date yet *models.NotPublishedError is returned.
+func (s *Service) GetImageForDate(ctx context.Context, date time.Time) (*models.AlbumRecord, error) {
+	if err := s.calendar.validate(date, time.Now()); err != nil {
+		return nil, err
+	}
+
+	image, err := s.repo.FetchImage(ctx, date)
 	if err == nil { // eq nil
-		return url, nil
+		return image, s.setReadURLs(ctx, image)
 	}
 
 	if errors.Is(err, models.ErrImageNotExists) {
-		downErr := s.downloadImage(ctx, date)
+		downErr := s.downloadImage(ctx, date, nil)
 		// possible situation where downloadImage returned error. But image already exists.
 		// So check error only if image not exists.
 
-		url, err := s.repo.FetchImagePath(ctx, date)
+		image, err := s.repo.FetchImage(ctx, date)
 		if err != nil {
 			if downErr != nil {
-				return "", downErr
+				return nil, s.calendar.notPublished(date, time.Now(), downErr, s.failureRetry)
 			}
 
-			return "", err
+			return nil, err
 		}
 
-		return url, nil
+		return image, s.setReadURLs(ctx, image)
 	}
 
-	return "", fmt.Errorf("fetch image path: %w", err)
+	return nil, fmt.Errorf("fetch image: %w", err)
+}
+
+// GetTodayImage returns the record of the latest APOD which is expected to be published,
+// days roll over in the publication timezone.
+func (s *Service) GetTodayImage(ctx context.Context) (*models.AlbumRecord, error) {
+	return s.GetImageForDate(ctx, s.calendar.today(time.Now()))
 }
 
 // downloadImage is function which downloads image from apod and uploads it to the storage.
 // If it is called concurrently, only one operation of downloading and saving is performed.
-func (s *Service) downloadImage(ctx context.Context, date time.Time) error {
-	waiter := make(chan error)
+// Download is not bound to ctx, cancellation of ctx only stops waiting for it.
+func (s *Service) downloadImage(ctx context.Context, date time.Time, apod *models.APOD) error {
+	waiter := make(chan error, 1)
 
-	s.downloader.registerDownloadWaiter(ctx, date, waiter)
+	s.downloader.registerDownloadWaiter(date, apod, waiter)
 
-	resErr := <-waiter
-	if resErr != nil {
+	select {
+	case resErr := <-waiter:
 		return resErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetAlbum returns the page of the album selected by the query.
+// The page is selected by the date of the cursor, so it is stable when new records are added.
+func (s *Service) GetAlbum(ctx context.Context, query models.AlbumQuery) (*models.AlbumPage, error) {
+	if query.Limit <= 0 {
+		query.Limit = defaultAlbumLimit
+	}
+
+	from, to := query.From, query.To
+	cursor := query.Cursor
+	backward := cursor != nil && cursor.Before
+
+	if cursor != nil {
+		// records after the cursor in the ascending order and before it in the descending one have greater dates.
+		if query.Descending == cursor.Before {
+			if next := cursor.Date.AddDate(0, 0, 1); from.IsZero() || next.After(from) {
+				from = next
+			}
+		} else {
+			if prev := cursor.Date.AddDate(0, 0, -1); to.IsZero() || prev.Before(to) {
+				to = prev
+			}
+		}
+	}
+
+	page := &models.AlbumPage{}
+
+	total, err := s.repo.CountAlbum(ctx, query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+
+	page.Total = total
+
+	if !to.IsZero() && to.Before(from) {
+		return page, nil
+	}
+
+	// pages before the cursor are fetched in the reversed order starting from the cursor.
+	records, err := s.repo.FetchAlbumPage(ctx, from, to, query.Descending != backward, query.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(records) > query.Limit
+	if hasMore {
+		records = records[:query.Limit]
+	}
+
+	if backward {
+		for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+			records[i], records[j] = records[j], records[i]
+		}
+	}
+
+	for i := range records {
+		if err := s.setReadURLs(ctx, &records[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	page.Records = records
+
+	if len(records) == 0 {
+		return page, nil
+	}
+
+	first, last := records[0].Date, records[len(records)-1].Date
+
+	if hasMore || backward {
+		page.Next = &models.AlbumCursor{Date: last}
+	}
+
+	if (hasMore && backward) || (!backward && cursor != nil) {
+		page.Prev = &models.AlbumCursor{Date: first, Before: true}
+	}
+
+	return page, nil
+}
+
+// setReadURLs sets links by which clients read the images of the record.
+func (s *Service) setReadURLs(ctx context.Context, record *models.AlbumRecord) error {
+	images := []struct {
+		quality models.Quality
+		ref     models.ObjectRef
+		url     *string
+	}{
+		{quality: models.QualityStandard, ref: record.Image, url: &record.URL},
+		{quality: models.QualityHD, ref: record.HDImage, url: &record.HDImageURL},
+	}
+
+	for _, img := range images {
+		if img.ref.IsZero() {
+			continue
+		}
+
+		if s.mediaBaseURL != "" {
+			*img.url = s.mediaURL(record.Date, img.quality)
+
+			continue
+		}
+
+		url, err := s.urls.URL(ctx, img.ref.Bucket, img.ref.Key)
+		if err != nil {
+			return fmt.Errorf("build url of %q: %w", img.ref.Key, err)
+		}
+
+		*img.url = url
+	}
+
+	for i := range record.Renditions {
+		rend := &record.Renditions[i]
+
+		if s.mediaBaseURL != "" {
+			rend.URL = s.mediaBaseURL + "/media/" + record.Date.Format(time.DateOnly) + "?width=" + strconv.Itoa(rend.Width)
+
+			continue
+		}
+
+		url, err := s.urls.URL(ctx, rend.Object.Bucket, rend.Object.Key)
+		if err != nil {
+			return fmt.Errorf("build url of %q: %w", rend.Object.Key, err)
+		}
+
+		rend.URL = url
 	}
 
 	return nil
 }
 
-func (s *Service) GetAlbum(ctx context.Context) ([]models.AlbumRecord, error) {
-	urls, err := s.repo.FetchAlbum(ctx)
+// mediaURL returns the link to the image served by the application.
+func (s *Service) mediaURL(date time.Time, quality models.Quality) string {
+	url := s.mediaBaseURL + "/media/" + date.Format(time.DateOnly)
+	if quality == models.QualityHD {
+		url += "?quality=" + string(quality)
+	}
+
+	return url
+}
+
+// OpenImage opens the stored image of the date, the image is downloaded if it is not stored yet.
+// If width is positive the rendition of the width is opened instead of the image of the quality.
+// If the record has no such image models.ErrFileNotExists is returned.
+func (s *Service) OpenImage(ctx context.Context, date time.Time, quality models.Quality, width int) (*models.MediaFile, error) {
+	record, err := s.GetImageForDate(ctx, date)
 	if err != nil {
-		return nil, fmt.Errorf("fetch all images: %w", err)
+		return nil, err
+	}
+
+	ref, info := record.ImageObject(quality)
+
+	if width > 0 {
+		ref = models.ObjectRef{}
+
+		for _, rend := range record.Renditions {
+			if rend.Width == width {
+				ref, info = rend.Object, rend.File
+			}
+		}
+	}
+
+	if ref.IsZero() {
+		return nil, models.ErrFileNotExists
+	}
+
+	stat, err := s.storage.StatFile(ctx, ref.Bucket, ref.Key)
+	if err != nil {
+		return nil, fmt.Errorf("stat file %q: %w", ref.Key, err)
+	}
+
+	content, err := s.storage.OpenFile(ctx, ref.Bucket, ref.Key)
+	if err != nil {
+		return nil, fmt.Errorf("open file %q: %w", ref.Key, err)
+	}
+
+	info.Name = stat.Name
+	info.Size = stat.Size
+	info.LastModified = stat.LastModified
+
+	if info.ContentType == "" {
+		info.ContentType = stat.ContentType
 	}
 
-	return urls, nil
+	return &models.MediaFile{Content: content, Info: info}, nil
 }
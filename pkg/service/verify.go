@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+// Verify checks that every file referenced by the records exists in the storage
+// and has the recorded size and hash.
+// If repair is true broken files are removed and images of their dates are downloaded again.
+func (s *Service) Verify(ctx context.Context, repair bool) (*models.VerifyReport, error) {
+	album, err := s.repo.FetchAlbum(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch album: %w", err)
+	}
+
+	report := &models.VerifyReport{}
+	broken := make(map[time.Time][]models.ObjectRef)
+
+	for i := range album {
+		record := &album[i]
+
+		files := []struct {
+			quality models.Quality
+			ref     models.ObjectRef
+			info    models.FileInfo
+		}{
+			{quality: models.QualityStandard, ref: record.Image, info: record.ImageFile},
+			{quality: models.QualityHD, ref: record.HDImage, info: record.HDImageFile},
+		}
+
+		for _, f := range files {
+			if f.ref.IsZero() {
+				continue
+			}
+
+			report.Checked++
+
+			problem, err := s.verifyFile(ctx, f.ref, f.info)
+			if err != nil {
+				return report, fmt.Errorf("verify %v %s image: %w", record.Date.Format(time.DateOnly), f.quality, err)
+			}
+
+			if problem == "" {
+				continue
+			}
+
+			report.Problems = append(report.Problems, models.VerifyProblem{
+				Date:    record.Date,
+				Quality: f.quality,
+				Name:    f.ref.Key,
+				Problem: problem,
+			})
+			broken[record.Date] = append(broken[record.Date], f.ref)
+		}
+	}
+
+	if !repair {
+		return report, nil
+	}
+
+	var errs []error
+
+	for date, refs := range broken {
+		if err := s.repairDate(ctx, date, refs); err != nil {
+			errs = append(errs, fmt.Errorf("repair %v: %w", date.Format(time.DateOnly), err))
+
+			continue
+		}
+
+		report.Repaired = append(report.Repaired, date)
+	}
+
+	return report, errors.Join(errs...)
+}
+
+// verifyFile returns the description of the problem of the file, or empty string if the file is consistent.
+func (s *Service) verifyFile(ctx context.Context, ref models.ObjectRef, info models.FileInfo) (string, error) {
+	stat, err := s.storage.StatFile(ctx, ref.Bucket, ref.Key)
+	if errors.Is(err, models.ErrFileNotExists) {
+		return "missing", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	// records saved before the file info was stored can be checked only for existence.
+	if info.Size > 0 && stat.Size != info.Size {
+		return fmt.Sprintf("size %v, expected %v", stat.Size, info.Size), nil
+	}
+
+	if info.SHA256 == "" {
+		return "", nil
+	}
+
+	file, err := s.storage.OpenFile(ctx, ref.Bucket, ref.Key)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != info.SHA256 {
+		return fmt.Sprintf("sha256 %s, expected %s", sum, info.SHA256), nil
+	}
+
+	return "", nil
+}
+
+// repairDate removes broken files of the date and downloads its images again.
+func (s *Service) repairDate(ctx context.Context, date time.Time, refs []models.ObjectRef) error {
+	for _, ref := range refs {
+		err := s.storage.DeleteFile(ctx, ref.Bucket, ref.Key)
+		if err != nil && !errors.Is(err, models.ErrFileNotExists) {
+			return fmt.Errorf("delete broken file %q: %w", ref.Key, err)
+		}
+	}
+
+	return s.downloadImage(ctx, date, nil)
+}
@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+const (
+	defaultFSRoot    = "./data/storage"
+	defaultFSBaseURL = "http://localhost:8080/files"
+
+	dirPerm = 0o755
+)
+
+// ErrInvalidFilename is returned when the name of the file is not a local path inside the bucket.
+var ErrInvalidFilename = errors.New("invalid filename")
+
+// FSConfig is a config of the local filesystem storage.
+// Files are stored at Root/bucket/filename and are available at BaseURL/bucket/filename.
+type FSConfig struct {
+	Root    string
+	BaseURL string
+}
+
+func InitFSConfig() *FSConfig {
+	return &FSConfig{
+		Root:    getEnvDefault("FS_ROOT", defaultFSRoot),
+		BaseURL: strings.TrimSuffix(getEnvDefault("FS_BASE_URL", defaultFSBaseURL), "/"),
+	}
+}
+
+// FS is a storage which keeps files in the directory of the local filesystem.
+// Files should be served by the application with the Handler.
+type FS struct {
+	root    string
+	baseURL string
+}
+
+// NewFSStorage is a constructor to the FS, the root directory is created if it does not exist.
+func NewFSStorage(cfg FSConfig) (*FS, error) {
+	if err := os.MkdirAll(cfg.Root, dirPerm); err != nil {
+		return nil, fmt.Errorf("create root directory: %w", err)
+	}
+
+	return &FS{root: cfg.Root, baseURL: cfg.BaseURL}, nil
+}
+
+// BaseURL method returns the address where the application serves the files.
+func (s *FS) BaseURL() string {
+	return s.baseURL
+}
+
+// UploadFile method writes the file to the temporary file and moves it to its place,
+// so partially written files are never visible.
+func (s *FS) UploadFile(_ context.Context, bucket, filename string, data io.Reader, _ int64) error {
+	p, err := s.path(bucket, filename)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), dirPerm); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, data); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("rename file: %w", err)
+	}
+
+	return nil
+}
+
+// StatFile method returns the information of the stored file.
+// If the file does not exist models.ErrFileNotExists is returned.
+func (s *FS) StatFile(_ context.Context, bucket, filename string) (*models.FileInfo, error) {
+	p, err := s.path(bucket, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, models.ErrFileNotExists
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	}
+
+	return fileInfo(filename, info), nil
+}
+
+// ListFiles method returns information of all files in the bucket.
+// If the bucket does not exist no files are returned.
+func (s *FS) ListFiles(_ context.Context, bucket string) ([]models.FileInfo, error) {
+	dir, err := s.path(bucket, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []models.FileInfo
+
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, *fileInfo(filepath.ToSlash(name), info))
+
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("walk bucket: %w", err)
+	}
+
+	return files, nil
+}
+
+// OpenFile method returns the content of the file, the caller should close it.
+// If the file does not exist models.ErrFileNotExists is returned.
+func (s *FS) OpenFile(ctx context.Context, bucket, filename string) (io.ReadSeekCloser, error) {
+	if _, err := s.StatFile(ctx, bucket, filename); err != nil {
+		return nil, err
+	}
+
+	p, err := s.path(bucket, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, models.ErrFileNotExists
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+
+	return f, nil
+}
+
+// TouchFile method sets the modification time of the file to the current time.
+// If the file does not exist models.ErrFileNotExists is returned.
+func (s *FS) TouchFile(ctx context.Context, bucket, filename string) error {
+	if _, err := s.StatFile(ctx, bucket, filename); err != nil {
+		return err
+	}
+
+	p, err := s.path(bucket, filename)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	err = os.Chtimes(p, now, now)
+	if errors.Is(err, fs.ErrNotExist) {
+		return models.ErrFileNotExists
+	}
+
+	if err != nil {
+		return fmt.Errorf("touch file: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteFile method removes the file from the storage, removing of not existing file is not an error.
+// Directories of the file which become empty are removed too.
+func (s *FS) DeleteFile(_ context.Context, bucket, filename string) error {
+	p, err := s.path(bucket, filename)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(p)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove file: %w", err)
+	}
+
+	bucketDir := filepath.Join(s.root, bucket)
+	for dir := filepath.Dir(p); dir != bucketDir; dir = filepath.Dir(dir) {
+		// removing fails when the directory is not empty.
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Handler returns the handler which serves the stored files by the path bucket/filename.
+// Directories are not listed.
+func (s *FS) Handler() http.Handler {
+	files := http.FileServer(http.Dir(s.root))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Base(r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/") || strings.HasPrefix(name, ".") {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		files.ServeHTTP(w, r)
+	})
+}
+
+// path returns the path of the file in the filesystem.
+// Names which can point outside of the bucket are rejected with ErrInvalidFilename.
+func (s *FS) path(bucket, filename string) (string, error) {
+	if bucket == "" || strings.ContainsAny(bucket, `/\`) || strings.HasPrefix(bucket, ".") {
+		return "", fmt.Errorf("%w: bucket %q", ErrInvalidFilename, bucket)
+	}
+
+	if filename == "" {
+		return filepath.Join(s.root, bucket), nil
+	}
+
+	name := filepath.FromSlash(filename)
+	if !filepath.IsLocal(name) || strings.HasPrefix(path.Base(filename), ".") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidFilename, filename)
+	}
+
+	return filepath.Join(s.root, bucket, name), nil
+}
+
+func fileInfo(name string, info fs.FileInfo) *models.FileInfo {
+	return &models.FileInfo{
+		Name:         name,
+		Size:         info.Size(),
+		ContentType:  getMimeType(name),
+		LastModified: info.ModTime(),
+	}
+}
+
+func getEnvDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return def
+}
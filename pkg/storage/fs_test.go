@@ -0,0 +1,20 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dyleme/apod.git/pkg/storage"
+	"github.com/Dyleme/apod.git/pkg/storage/storagetest"
+)
+
+func TestFSConformance(t *testing.T) {
+	stor, err := storage.NewFSStorage(storage.FSConfig{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storagetest.Run(context.Background(), stor); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+)
+
+type memoryFile struct {
+	data         []byte
+	lastModified time.Time
+}
+
+// Memory is a storage which keeps files in memory, it is intended for tests.
+type Memory struct {
+	mx      sync.RWMutex
+	buckets map[string]map[string]memoryFile
+}
+
+func NewMemoryStorage() *Memory {
+	return &Memory{buckets: make(map[string]map[string]memoryFile)}
+}
+
+// BaseURL method returns the base of the links to the files, links can not be opened outside of the process.
+func (m *Memory) BaseURL() string {
+	return "memory://storage"
+}
+
+// UploadFile method reads the whole file and stores it, the file of the same name is replaced.
+func (m *Memory) UploadFile(ctx context.Context, bucket, filename string, data io.Reader, _ int64) error {
+	bts, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.buckets[bucket] == nil {
+		m.buckets[bucket] = make(map[string]memoryFile)
+	}
+
+	m.buckets[bucket][filename] = memoryFile{data: bts, lastModified: time.Now()}
+
+	return nil
+}
+
+// StatFile method returns the information of the stored file.
+// If the file does not exist models.ErrFileNotExists is returned.
+func (m *Memory) StatFile(_ context.Context, bucket, filename string) (*models.FileInfo, error) {
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+
+	f, ok := m.buckets[bucket][filename]
+	if !ok {
+		return nil, models.ErrFileNotExists
+	}
+
+	return f.info(filename), nil
+}
+
+// ListFiles method returns information of all files in the bucket sorted by name.
+func (m *Memory) ListFiles(_ context.Context, bucket string) ([]models.FileInfo, error) {
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+
+	var files []models.FileInfo
+	for name, f := range m.buckets[bucket] {
+		files = append(files, *f.info(name))
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	return files, nil
+}
+
+// OpenFile method returns the content of the file.
+// If the file does not exist models.ErrFileNotExists is returned.
+func (m *Memory) OpenFile(_ context.Context, bucket, filename string) (io.ReadSeekCloser, error) {
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+
+	f, ok := m.buckets[bucket][filename]
+	if !ok {
+		return nil, models.ErrFileNotExists
+	}
+
+	// stored data is never modified, uploads replace the whole slice.
+	return memoryReader{bytes.NewReader(f.data)}, nil
+}
+
+// TouchFile method sets the modification time of the file to the current time.
+// If the file does not exist models.ErrFileNotExists is returned.
+func (m *Memory) TouchFile(_ context.Context, bucket, filename string) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	f, ok := m.buckets[bucket][filename]
+	if !ok {
+		return models.ErrFileNotExists
+	}
+
+	f.lastModified = time.Now()
+	m.buckets[bucket][filename] = f
+
+	return nil
+}
+
+// DeleteFile method removes the file from the storage, removing of not existing file is not an error.
+func (m *Memory) DeleteFile(_ context.Context, bucket, filename string) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	delete(m.buckets[bucket], filename)
+
+	return nil
+}
+
+type memoryReader struct {
+	*bytes.Reader
+}
+
+func (memoryReader) Close() error {
+	return nil
+}
+
+func (f memoryFile) info(name string) *models.FileInfo {
+	return &models.FileInfo{
+		Name:         name,
+		Size:         int64(len(f.data)),
+		ContentType:  getMimeType(name),
+		LastModified: f.lastModified,
+	}
+}
@@ -0,0 +1,15 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dyleme/apod.git/pkg/storage"
+	"github.com/Dyleme/apod.git/pkg/storage/storagetest"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	if err := storagetest.Run(context.Background(), storage.NewMemoryStorage()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,31 +1,51 @@
 package storage
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"mime"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Dyleme/apod.git/pkg/models"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
+const (
+	// unknownSizePartSize is the part size of the multipart upload of the files with unknown size.
+	// It is the minimal part size allowed by S3.
+	unknownSizePartSize = 5 << 20
+
+	defaultRegion        = "us-east-1"
+	defaultPresignExpiry = time.Hour
+	maxPresignExpiry     = 7 * 24 * time.Hour
+)
+
 // Minio is a struct that provides methods to store files in minio storage.
+// In the private mode buckets are created without the public read policy
+// and files are read by presigned urls.
 type Minio struct {
-	client           minio.Client
-	externalEndpoint string
+	client        minio.Client
+	presignClient *minio.Client
+	private       bool
+	presignExpiry time.Duration
 }
 
 // MinioConnfig is a config to make connection with minio storage.
+// Region is used to presign urls without requesting the location of the bucket.
 type Config struct {
 	Endpoint         string
 	AccessKeyID      string
 	SecretAccessKey  string
 	UseSSL           bool
 	ExternalEndpoint string
+	Region           string
+	Private          bool
+	PresignExpiry    time.Duration
 }
 
 func InitConfig() (*Config, error) {
@@ -37,61 +57,235 @@ func InitConfig() (*Config, error) {
 	externalHost := os.Getenv("MN_EXTERNAL_HOST")
 	externalPort := os.Getenv("MN_EXTERNAL_PORT")
 
+	private := os.Getenv("MN_PRIVATE_BUCKET")
+	presignExpiry := os.Getenv("MN_PRESIGN_EXPIRY")
+
 	useSsl, err := strconv.ParseBool(ssl)
 	if err != nil {
 		return nil, fmt.Errorf("cant parse %q into bool: %w", ssl, err)
 	}
 
-	return &Config{
+	cfg := &Config{
 		Endpoint:         mnhost + ":" + mnport,
 		AccessKeyID:      accessKey,
 		SecretAccessKey:  secretKey,
 		UseSSL:           useSsl,
 		ExternalEndpoint: externalHost + ":" + externalPort,
-	}, nil
+		Region:           getEnvDefault("MN_REGION", defaultRegion),
+		PresignExpiry:    defaultPresignExpiry,
+	}
+
+	if private != "" {
+		cfg.Private, err = strconv.ParseBool(private)
+		if err != nil {
+			return nil, fmt.Errorf("cant parse %q into bool: %w", private, err)
+		}
+	}
+
+	if presignExpiry != "" {
+		cfg.PresignExpiry, err = time.ParseDuration(presignExpiry)
+		if err != nil {
+			return nil, fmt.Errorf("cant parse presign expiry %q: %w", presignExpiry, err)
+		}
+
+		if cfg.PresignExpiry <= 0 || cfg.PresignExpiry > maxPresignExpiry {
+			return nil, fmt.Errorf("presign expiry %v should be positive and not greater than %v", cfg.PresignExpiry, maxPresignExpiry)
+		}
+	}
+
+	return cfg, nil
 }
 
 // NewMinoStorage is a constructor to the MinioStoage.
 // Returns error if the connection is denied.
 func NewMinioStorage(cfg Config) (*Minio, error) {
+	creds := credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+
 	cl, err := minio.New(cfg.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Creds:  creds,
 		Secure: cfg.UseSSL,
+		Region: cfg.Region,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("can not initialize storage: %w", err)
 	}
 
-	return &Minio{client: *cl, externalEndpoint: cfg.ExternalEndpoint}, nil
+	// the signature covers the host, so urls for clients are presigned for the external endpoint.
+	// The client only signs urls and builds the base url, it does not make requests.
+	presignCl, err := minio.New(cfg.ExternalEndpoint, &minio.Options{
+		Creds:  creds,
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not initialize presign client: %w", err)
+	}
+
+	return &Minio{
+		client:        *cl,
+		presignClient: presignCl,
+		private:       cfg.Private,
+		presignExpiry: cfg.PresignExpiry,
+	}, nil
+}
+
+// BaseURL method returns the external address of the storage, the scheme depends on the ssl config.
+func (m *Minio) BaseURL() string {
+	return m.presignClient.EndpointURL().String()
 }
 
-func (m *Minio) GetURL(bucket, filename string) string {
-	return "http://" + m.externalEndpoint + "/" + bucket + "/" + filename
+// Private method reports whether the buckets are private and files should be read by presigned urls.
+func (m *Minio) Private() bool {
+	return m.private
+}
+
+// PresignGetURL method returns the url to read the file which expires after the configured time.
+func (m *Minio) PresignGetURL(ctx context.Context, bucket, filename string) (string, error) {
+	u, err := m.presignClient.PresignedGetObject(ctx, bucket, filename, m.presignExpiry, nil)
+	if err != nil {
+		return "", fmt.Errorf("presign get object: %w", err)
+	}
+
+	return u.String(), nil
 }
 
-// UploadFile method upload provided file to the minio storage and returns path to the file.
-func (m *Minio) UploadFile(ctx context.Context, bucket, filename string, data []byte) (string, error) {
+// UploadFile method streams provided file to the minio storage.
+// If the size is unknown it should be -1, then the file is uploaded by parts of the limited size.
+// The service spools images to temporary files and always knows the size,
+// the unknown size is kept supported for the Storager contract and is checked by the storagetest suite.
+func (m *Minio) UploadFile(ctx context.Context, bucket, filename string, data io.Reader, size int64) error {
 	exist, err := m.client.BucketExists(ctx, bucket)
 	if err != nil {
-		return "", fmt.Errorf("check bucket existing: %w", err)
+		return fmt.Errorf("check bucket existing: %w", err)
 	}
 
 	if !exist {
-		err := m.createPublicBucket(ctx, bucket)
+		err := m.createBucket(ctx, bucket)
 		if err != nil {
-			return "", err
+			return err
+		}
+	}
+
+	opts := minio.PutObjectOptions{ContentType: getMimeType(filename)}
+	if size < 0 {
+		// without the part size minio allocates the buffer for the biggest possible object.
+		opts.PartSize = unknownSizePartSize
+	}
+
+	_, err = m.client.PutObject(ctx, bucket, filename, data, size, opts)
+
+	if err != nil {
+		return fmt.Errorf("can not upload file: %w", err)
+	}
+
+	return nil
+}
+
+// StatFile method returns the information of the stored file.
+// If the file or the bucket does not exist models.ErrFileNotExists is returned.
+func (m *Minio) StatFile(ctx context.Context, bucket, filename string) (*models.FileInfo, error) {
+	info, err := m.client.StatObject(ctx, bucket, filename, minio.StatObjectOptions{})
+	if err != nil {
+		switch minio.ToErrorResponse(err).Code {
+		case "NoSuchKey", "NoSuchBucket":
+			return nil, models.ErrFileNotExists
+		default:
+			return nil, fmt.Errorf("stat object: %w", err)
+		}
+	}
+
+	return &models.FileInfo{
+		Name:         info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified,
+	}, nil
+}
+
+// ListFiles method returns information of all files in the bucket.
+// If the bucket does not exist no files are returned.
+func (m *Minio) ListFiles(ctx context.Context, bucket string) ([]models.FileInfo, error) {
+	exist, err := m.client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("check bucket existing: %w", err)
+	}
+
+	if !exist {
+		return nil, nil
+	}
+
+	var files []models.FileInfo
+
+	for obj := range m.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("list objects: %w", obj.Err)
 		}
+
+		files = append(files, models.FileInfo{
+			Name:         obj.Key,
+			Size:         obj.Size,
+			ContentType:  obj.ContentType,
+			LastModified: obj.LastModified,
+		})
+	}
+
+	return files, nil
+}
+
+// OpenFile method returns the content of the file, the caller should close it.
+// Seeking the file makes the new request of the object from the offset.
+// If the file does not exist models.ErrFileNotExists is returned.
+func (m *Minio) OpenFile(ctx context.Context, bucket, filename string) (io.ReadSeekCloser, error) {
+	// GetObject does not make request until the first read, so stat the file to check its existence.
+	if _, err := m.StatFile(ctx, bucket, filename); err != nil {
+		return nil, err
+	}
+
+	obj, err := m.client.GetObject(ctx, bucket, filename, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get object: %w", err)
+	}
+
+	return obj, nil
+}
+
+// TouchFile method sets the modification time of the object to the current time
+// by copying the object onto itself, the content type is kept.
+// If the object does not exist models.ErrFileNotExists is returned.
+func (m *Minio) TouchFile(ctx context.Context, bucket, filename string) error {
+	info, err := m.StatFile(ctx, bucket, filename)
+	if err != nil {
+		return err
 	}
 
-	bf := bytes.NewBuffer(data)
+	_, err = m.client.CopyObject(ctx,
+		minio.CopyDestOptions{
+			Bucket:          bucket,
+			Object:          filename,
+			ReplaceMetadata: true,
+			UserMetadata:    map[string]string{"Content-Type": info.ContentType},
+		},
+		minio.CopySrcOptions{Bucket: bucket, Object: filename},
+	)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return models.ErrFileNotExists
+		}
+
+		return fmt.Errorf("copy object: %w", err)
+	}
 
-	_, err = m.client.PutObject(ctx, bucket, filename, bf, int64(bf.Len()), minio.PutObjectOptions{ContentType: getMimeType(filename)})
+	return nil
+}
 
+// DeleteFile method removes the file from the storage.
+func (m *Minio) DeleteFile(ctx context.Context, bucket, filename string) error {
+	err := m.client.RemoveObject(ctx, bucket, filename, minio.RemoveObjectOptions{})
 	if err != nil {
-		return "", fmt.Errorf("can not upload file: %w", err)
+		return fmt.Errorf("remove object: %w", err)
 	}
 
-	return m.GetURL(bucket, filename), nil
+	return nil
 }
 
 func getMimeType(filename string) string {
@@ -104,17 +298,44 @@ func getMimeType(filename string) string {
 	return mime.TypeByExtension(ext)
 }
 
-func (m *Minio) createPublicBucket(ctx context.Context, bucket string) error {
+// ApplyBucketPolicies method sets the policies of the existing buckets according to the mode,
+// so the mode can be switched for the buckets created before.
+func (m *Minio) ApplyBucketPolicies(ctx context.Context) error {
+	buckets, err := m.client.ListBuckets(ctx)
+	if err != nil {
+		return fmt.Errorf("list buckets: %w", err)
+	}
+
+	for _, b := range buckets {
+		if err := m.setBucketPolicy(ctx, b.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createBucket creates the bucket, in the public mode everyone is allowed to read its files.
+func (m *Minio) createBucket(ctx context.Context, bucket string) error {
 	err := m.client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{})
 	if err != nil {
 		return fmt.Errorf("make bucket: %w", err)
 	}
 
+	return m.setBucketPolicy(ctx, bucket)
+}
+
+// setBucketPolicy allows everyone to read files of the bucket in the public mode and removes the policy in the private one.
+func (m *Minio) setBucketPolicy(ctx context.Context, bucket string) error {
 	const publicPolicy = `{"Version": "2012-10-17","Statement": [{"Action": ["s3:GetObject"],"Effect": "Allow","Principal": {"AWS": ["*"]},"Resource": ["arn:aws:s3:::%s/*"],"Sid": ""}]}`
 
-	err = m.client.SetBucketPolicy(ctx, bucket, fmt.Sprintf(publicPolicy, bucket))
-	if err != nil {
-		return fmt.Errorf("set bucket policy: %w", err)
+	policy := ""
+	if !m.private {
+		policy = fmt.Sprintf(publicPolicy, bucket)
+	}
+
+	if err := m.client.SetBucketPolicy(ctx, bucket, policy); err != nil {
+		return fmt.Errorf("set policy of bucket %q: %w", bucket, err)
 	}
 
 	return nil
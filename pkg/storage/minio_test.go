@@ -0,0 +1,35 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Dyleme/apod.git/pkg/storage"
+	"github.com/Dyleme/apod.git/pkg/storage/storagetest"
+)
+
+// TestMinioConformance runs the suite against the minio configured by the MN_ variables, like the application.
+func TestMinioConformance(t *testing.T) {
+	if os.Getenv("MN_HOST") == "" {
+		t.Skip("MN_HOST is not set")
+	}
+
+	cfg, err := storage.InitConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if os.Getenv("MN_EXTERNAL_HOST") == "" {
+		cfg.ExternalEndpoint = cfg.Endpoint
+	}
+
+	stor, err := storage.NewMinioStorage(*cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storagetest.Run(context.Background(), stor); err != nil {
+		t.Fatal(err)
+	}
+}
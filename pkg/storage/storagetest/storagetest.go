@@ -0,0 +1,258 @@
+// Package storagetest provides the conformance suite which every storage driver should pass.
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Dyleme/apod.git/pkg/models"
+	"github.com/Dyleme/apod.git/pkg/service"
+)
+
+// Bucket is the bucket used by the suite, files of it are removed when the suite finishes.
+const Bucket = "storagetest"
+
+type check struct {
+	name string
+	run  func(ctx context.Context, s service.Storager) error
+}
+
+var checks = []check{
+	{"missing file", checkMissing},
+	{"missing bucket", checkMissingBucket},
+	{"upload with known size", checkUpload(0)},
+	{"upload with unknown size", checkUpload(-1)},
+	{"nested names", checkNested},
+	{"overwrite", checkOverwrite},
+	{"delete", checkDelete},
+	{"touch", checkTouch},
+}
+
+// Run runs the suite against the storage, failures of all checks are joined into the returned error.
+func Run(ctx context.Context, s service.Storager) error {
+	var errs []error
+
+	for _, c := range checks {
+		if err := c.run(ctx, s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+
+		if err := cleanup(ctx, s); err != nil {
+			return errors.Join(append(errs, fmt.Errorf("cleanup after %s: %w", c.name, err))...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func checkMissing(ctx context.Context, s service.Storager) error {
+	if _, err := s.StatFile(ctx, Bucket, "missing.jpg"); !errors.Is(err, models.ErrFileNotExists) {
+		return fmt.Errorf("stat: expected %v, got %v", models.ErrFileNotExists, err)
+	}
+
+	if _, err := s.OpenFile(ctx, Bucket, "missing.jpg"); !errors.Is(err, models.ErrFileNotExists) {
+		return fmt.Errorf("open: expected %v, got %v", models.ErrFileNotExists, err)
+	}
+
+	if err := s.DeleteFile(ctx, Bucket, "missing.jpg"); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+func checkMissingBucket(ctx context.Context, s service.Storager) error {
+	files, err := s.ListFiles(ctx, Bucket)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+
+	if len(files) != 0 {
+		return fmt.Errorf("list: expected no files, got %v", len(files))
+	}
+
+	return nil
+}
+
+// checkUpload uploads the file with provided size, which is replaced by the real one if it is not negative.
+func checkUpload(size int64) func(ctx context.Context, s service.Storager) error {
+	return func(ctx context.Context, s service.Storager) error {
+		data := []byte("conformance suite content")
+		if size >= 0 {
+			size = int64(len(data))
+		}
+
+		if err := s.UploadFile(ctx, Bucket, "file.jpg", bytes.NewReader(data), size); err != nil {
+			return fmt.Errorf("upload: %w", err)
+		}
+
+		info, err := s.StatFile(ctx, Bucket, "file.jpg")
+		if err != nil {
+			return fmt.Errorf("stat: %w", err)
+		}
+
+		switch {
+		case info.Name != "file.jpg":
+			return fmt.Errorf("stat: expected name %q, got %q", "file.jpg", info.Name)
+		case info.Size != int64(len(data)):
+			return fmt.Errorf("stat: expected size %v, got %v", len(data), info.Size)
+		case !strings.HasPrefix(info.ContentType, "image/jpeg"):
+			return fmt.Errorf("stat: expected content type %q, got %q", "image/jpeg", info.ContentType)
+		case info.LastModified.IsZero():
+			return errors.New("stat: last modified is not set")
+		}
+
+		return checkContent(ctx, s, "file.jpg", data)
+	}
+}
+
+func checkNested(ctx context.Context, s service.Storager) error {
+	names := []string{"a/b/one.png", "a/two.png", "three.png"}
+
+	for _, name := range names {
+		if err := s.UploadFile(ctx, Bucket, name, strings.NewReader(name), int64(len(name))); err != nil {
+			return fmt.Errorf("upload %q: %w", name, err)
+		}
+	}
+
+	files, err := s.ListFiles(ctx, Bucket)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+
+	listed := make(map[string]int64, len(files))
+	for _, f := range files {
+		listed[f.Name] = f.Size
+	}
+
+	if len(listed) != len(names) {
+		return fmt.Errorf("list: expected %v files, got %v", len(names), len(listed))
+	}
+
+	for _, name := range names {
+		size, ok := listed[name]
+		if !ok {
+			return fmt.Errorf("list: file %q is not listed", name)
+		}
+
+		if size != int64(len(name)) {
+			return fmt.Errorf("list: expected size of %q %v, got %v", name, len(name), size)
+		}
+	}
+
+	return nil
+}
+
+func checkOverwrite(ctx context.Context, s service.Storager) error {
+	for _, content := range []string{"first version", "second"} {
+		if err := s.UploadFile(ctx, Bucket, "file.png", strings.NewReader(content), int64(len(content))); err != nil {
+			return fmt.Errorf("upload: %w", err)
+		}
+	}
+
+	return checkContent(ctx, s, "file.png", []byte("second"))
+}
+
+func checkDelete(ctx context.Context, s service.Storager) error {
+	if err := s.UploadFile(ctx, Bucket, "file.gif", strings.NewReader("gif"), 3); err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+
+	if err := s.DeleteFile(ctx, Bucket, "file.gif"); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	if _, err := s.StatFile(ctx, Bucket, "file.gif"); !errors.Is(err, models.ErrFileNotExists) {
+		return fmt.Errorf("stat after delete: expected %v, got %v", models.ErrFileNotExists, err)
+	}
+
+	return nil
+}
+
+func checkTouch(ctx context.Context, s service.Storager) error {
+	if err := s.TouchFile(ctx, Bucket, "missing.jpg"); !errors.Is(err, models.ErrFileNotExists) {
+		return fmt.Errorf("touch missing: expected %v, got %v", models.ErrFileNotExists, err)
+	}
+
+	if err := s.UploadFile(ctx, Bucket, "file.png", strings.NewReader("png"), 3); err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+
+	// modification times of some storages have the precision of a second.
+	time.Sleep(time.Second)
+
+	touched := time.Now().Truncate(time.Second)
+
+	if err := s.TouchFile(ctx, Bucket, "file.png"); err != nil {
+		return fmt.Errorf("touch: %w", err)
+	}
+
+	info, err := s.StatFile(ctx, Bucket, "file.png")
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+
+	if info.LastModified.Before(touched) {
+		return fmt.Errorf("stat: expected modification time after %v, got %v", touched, info.LastModified)
+	}
+
+	return checkContent(ctx, s, "file.png", []byte("png"))
+}
+
+func checkContent(ctx context.Context, s service.Storager, name string, expected []byte) error {
+	f, err := s.OpenFile(ctx, Bucket, name)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	if !bytes.Equal(data, expected) {
+		return fmt.Errorf("read: expected content %q, got %q", expected, data)
+	}
+
+	if len(expected) < 2 {
+		return nil
+	}
+
+	offset := int64(len(expected) / 2)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
+
+	data, err = io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("read after seek: %w", err)
+	}
+
+	if !bytes.Equal(data, expected[offset:]) {
+		return fmt.Errorf("read after seek: expected content %q, got %q", expected[offset:], data)
+	}
+
+	return nil
+}
+
+// cleanup removes all files of the suite bucket.
+func cleanup(ctx context.Context, s service.Storager) error {
+	files, err := s.ListFiles(ctx, Bucket)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+
+	for _, f := range files {
+		if err := s.DeleteFile(ctx, Bucket, f.Name); err != nil {
+			return fmt.Errorf("delete %q: %w", f.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// URLConfig is a config of the links to the stored files.
+// BaseURL replaces the address of the storage in links, it can be the CDN address or contain the path prefix.
+type URLConfig struct {
+	BaseURL string
+}
+
+func InitURLConfig() *URLConfig {
+	return &URLConfig{BaseURL: strings.TrimSuffix(os.Getenv("STORAGE_PUBLIC_URL"), "/")}
+}
+
+// Presigner returns temporary links to the files of the private storage.
+type Presigner interface {
+	PresignGetURL(ctx context.Context, bucket, filename string) (string, error)
+}
+
+// URLBuilder builds links to the stored files when they are read,
+// so the stored locations do not depend on the address of the storage.
+type URLBuilder struct {
+	baseURL   string
+	presigner Presigner
+}
+
+// NewURLBuilder returns the builder of links base/bucket/filename.
+// If presigner is not nil links are presigned by it and the base is not used.
+func NewURLBuilder(base string, presigner Presigner) *URLBuilder {
+	return &URLBuilder{baseURL: strings.TrimSuffix(base, "/"), presigner: presigner}
+}
+
+// URL method returns the link to the file.
+func (b *URLBuilder) URL(ctx context.Context, bucket, filename string) (string, error) {
+	if b.presigner != nil {
+		return b.presigner.PresignGetURL(ctx, bucket, filename)
+	}
+
+	return b.baseURL + "/" + bucket + "/" + filename, nil
+}